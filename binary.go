@@ -0,0 +1,446 @@
+package haat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"sort"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// binaryMagic starts the binary form of a tree, followed by the version.
+const binaryMagic = "HAAT\x01"
+
+// bundleMagic starts a bundle of the binary forms of documents, followed by the version.
+const bundleMagic = "HAATB\x01"
+
+// ErrAtomMismatch is returned when the binary form was encoded with a different atom table,
+// that is a different version of golang.org/x/net/html. Encode it again.
+var ErrAtomMismatch = errors.New("haat: binary form was encoded with a different atom table")
+
+// fingerprintAtoms are the atoms which identify the atom table in the binary form.
+var fingerprintAtoms = []atom.Atom{
+	atom.A, atom.Body, atom.Class, atom.Div, atom.Foreignobject, atom.Head, atom.Href, atom.Html,
+	atom.Id, atom.Li, atom.Math, atom.P, atom.Script, atom.Span, atom.Style, atom.Svg, atom.Table,
+	atom.Td, atom.Template, atom.Title, atom.Tr, atom.Ul,
+}
+
+// atomFingerprint is the hash of the ids and names of fingerprintAtoms.
+var atomFingerprint = func() uint64 {
+	h := fnv.New64a()
+	for _, a := range fingerprintAtoms {
+		binary.Write(h, binary.LittleEndian, uint32(a))
+		io.WriteString(h, a.String())
+	}
+	return h.Sum64()
+}()
+
+// binaryEncoder encodes nodes with the tag names, attribute keys and namespaces interned in the string table.
+type binaryEncoder struct {
+	index map[string]uint64
+	table []string
+	body  []byte
+}
+
+func newBinaryEncoder() *binaryEncoder {
+	return &binaryEncoder{index: map[string]uint64{"": 0}, table: []string{""}}
+}
+
+func (e *binaryEncoder) uvarint(v uint64) {
+	e.body = binary.AppendUvarint(e.body, v)
+}
+
+// intern writes the index of the string in the string table.
+func (e *binaryEncoder) intern(s string) {
+	i, ok := e.index[s]
+	if !ok {
+		i = uint64(len(e.table))
+		e.index[s] = i
+		e.table = append(e.table, s)
+	}
+	e.uvarint(i)
+}
+
+// inline writes the string itself.
+func (e *binaryEncoder) inline(s string) {
+	e.uvarint(uint64(len(s)))
+	e.body = append(e.body, s...)
+}
+
+// name writes the atom id if the name is an atom, otherwise the interned name.
+func (e *binaryEncoder) name(s string) {
+	if a := atom.Lookup([]byte(s)); a != 0 && a.String() == s {
+		e.uvarint(uint64(a)<<1 | 1)
+		return
+	}
+	e.uvarint(0)
+	e.intern(s)
+}
+
+func (e *binaryEncoder) node(n *html.Node) {
+	e.body = append(e.body, byte(n.Type))
+	switch n.Type {
+	case html.ElementNode:
+		// The atom is stored as is; the name follows only if it is not the name of the atom.
+		if n.DataAtom != 0 && n.DataAtom.String() == n.Data {
+			e.uvarint(uint64(n.DataAtom)<<1 | 1)
+		} else {
+			e.uvarint(uint64(n.DataAtom) << 1)
+			e.intern(n.Data)
+		}
+		e.intern(n.Namespace)
+	default:
+		e.inline(n.Data)
+	}
+	e.uvarint(uint64(len(n.Attr)))
+	for _, a := range n.Attr {
+		e.intern(a.Namespace)
+		e.name(a.Key)
+		e.inline(a.Val)
+	}
+	children := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		children++
+	}
+	e.uvarint(uint64(children))
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		e.node(c)
+	}
+}
+
+// bytes returns the header, the string table and the encoded nodes.
+func (e *binaryEncoder) bytes() []byte {
+	b := []byte(binaryMagic)
+	b = binary.LittleEndian.AppendUint64(b, atomFingerprint)
+	b = binary.AppendUvarint(b, uint64(len(e.table)))
+	for _, s := range e.table {
+		b = binary.AppendUvarint(b, uint64(len(s)))
+		b = append(b, s...)
+	}
+	return append(b, e.body...)
+}
+
+// AppendBinary appends the compact binary form of the node and its descendants to b.
+// Tag names, attribute keys and namespaces are interned in a string table,
+// and atoms are stored as their ids.
+func AppendBinary(b []byte, n Node) []byte {
+	e := newBinaryEncoder()
+	e.node(convertNode(n))
+	return append(b, e.bytes()...)
+}
+
+// EncodeBinary writes the compact binary form of the node and its descendants.
+func EncodeBinary(w io.Writer, n Node) error {
+	_, err := w.Write(AppendBinary(nil, n))
+	return err
+}
+
+// errBinaryTruncated is returned when the binary form ends unexpectedly.
+var errBinaryTruncated = errors.New("haat: binary form is truncated")
+
+// binaryDecoder decodes the binary form. The strings are sliced from a single copy of the data
+// and the nodes are allocated in blocks to reduce allocations.
+type binaryDecoder struct {
+	data  []byte
+	str   string
+	table []string
+	nodes []html.Node
+}
+
+// newNode returns a node allocated from the current block.
+func (d *binaryDecoder) newNode() *html.Node {
+	if len(d.nodes) == 0 {
+		d.nodes = make([]html.Node, 64)
+	}
+	n := &d.nodes[0]
+	d.nodes = d.nodes[1:]
+	return n
+}
+
+func (d *binaryDecoder) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		return 0, errBinaryTruncated
+	}
+	d.data = d.data[n:]
+	return v, nil
+}
+
+func (d *binaryDecoder) inline() (string, error) {
+	l, err := d.uvarint()
+	if err != nil {
+		return "", err
+	}
+	if uint64(len(d.data)) < l {
+		return "", errBinaryTruncated
+	}
+	var s string
+	if d.str != "" {
+		// d.str ends with the same bytes as d.data.
+		start := len(d.str) - len(d.data)
+		s = d.str[start : start+int(l)]
+	} else {
+		s = string(d.data[:l])
+	}
+	d.data = d.data[l:]
+	return s, nil
+}
+
+func (d *binaryDecoder) intern() (string, error) {
+	i, err := d.uvarint()
+	if err != nil {
+		return "", err
+	}
+	if i >= uint64(len(d.table)) {
+		return "", fmt.Errorf("haat: string index %d out of range", i)
+	}
+	return d.table[i], nil
+}
+
+// binaryAtom returns the atom of the id or an error if it is not a valid atom.
+func binaryAtom(v uint64) (atom.Atom, error) {
+	a := atom.Atom(v)
+	if uint64(a) != v || a.String() == "" {
+		return 0, ErrAtomMismatch
+	}
+	return a, nil
+}
+
+func (d *binaryDecoder) name() (string, error) {
+	v, err := d.uvarint()
+	if err != nil {
+		return "", err
+	}
+	if v&1 == 0 {
+		return d.intern()
+	}
+	a, err := binaryAtom(v >> 1)
+	if err != nil {
+		return "", err
+	}
+	return a.String(), nil
+}
+
+func (d *binaryDecoder) node() (*html.Node, error) {
+	if len(d.data) == 0 {
+		return nil, errBinaryTruncated
+	}
+	n := d.newNode()
+	n.Type = html.NodeType(d.data[0])
+	d.data = d.data[1:]
+	var err error
+	switch n.Type {
+	case html.ElementNode:
+		v, err := d.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if v>>1 != 0 {
+			if n.DataAtom, err = binaryAtom(v >> 1); err != nil {
+				return nil, err
+			}
+		}
+		if v&1 == 1 {
+			n.Data = n.DataAtom.String()
+		} else if n.Data, err = d.intern(); err != nil {
+			return nil, err
+		}
+		if n.Namespace, err = d.intern(); err != nil {
+			return nil, err
+		}
+	case html.DocumentNode, html.TextNode, html.CommentNode, html.DoctypeNode, html.RawNode:
+		if n.Data, err = d.inline(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("haat: unknown node type: %d", n.Type)
+	}
+
+	attrs, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if attrs > uint64(len(d.data)) {
+		return nil, errBinaryTruncated
+	}
+	if attrs > 0 {
+		n.Attr = make([]html.Attribute, attrs)
+	}
+	for i := range n.Attr {
+		a := &n.Attr[i]
+		if a.Namespace, err = d.intern(); err != nil {
+			return nil, err
+		}
+		if a.Key, err = d.name(); err != nil {
+			return nil, err
+		}
+		if a.Val, err = d.inline(); err != nil {
+			return nil, err
+		}
+	}
+
+	children, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	for range children {
+		c, err := d.node()
+		if err != nil {
+			return nil, err
+		}
+		n.AppendChild(c)
+	}
+	return n, nil
+}
+
+// DecodeBinary rebuilds the node encoded by EncodeBinary and returns it
+// as *Document, *Element, *Text, *RawText, *Doctype or *Comment.
+// It returns ErrAtomMismatch if the data was encoded with a different version of golang.org/x/net/html.
+func DecodeBinary(data []byte) (Node, error) {
+	if !bytes.HasPrefix(data, []byte(binaryMagic)) {
+		return nil, errors.New("haat: not a binary form of haat")
+	}
+	data = data[len(binaryMagic):]
+	if len(data) < 8 {
+		return nil, errBinaryTruncated
+	}
+	if binary.LittleEndian.Uint64(data) != atomFingerprint {
+		return nil, ErrAtomMismatch
+	}
+	d := &binaryDecoder{data: data[8:]}
+	d.str = string(d.data)
+	count, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if count > uint64(len(d.data))+1 {
+		return nil, errBinaryTruncated
+	}
+	d.table = make([]string, count)
+	for i := range d.table {
+		if d.table[i], err = d.inline(); err != nil {
+			return nil, err
+		}
+	}
+	n, err := d.node()
+	if err != nil {
+		return nil, err
+	}
+	if len(d.data) != 0 {
+		return nil, errors.New("haat: trailing data after binary form")
+	}
+	return wrapNode(n), nil
+}
+
+// Bundle is a set of documents in the binary form, keyed by name.
+type Bundle struct {
+	docs map[string][]byte
+}
+
+// LoadBundle loads the bundle written by WriteBundle, such as a file embedded with go:embed.
+// The documents are decoded by Document.
+func LoadBundle(data []byte) (*Bundle, error) {
+	if !bytes.HasPrefix(data, []byte(bundleMagic)) {
+		return nil, errors.New("haat: not a bundle of haat")
+	}
+	d := &binaryDecoder{data: data[len(bundleMagic):]}
+	count, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{docs: map[string][]byte{}}
+	for range count {
+		name, err := d.inline()
+		if err != nil {
+			return nil, err
+		}
+		l, err := d.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(d.data)) < l {
+			return nil, errBinaryTruncated
+		}
+		b.docs[name] = d.data[:l:l]
+		d.data = d.data[l:]
+	}
+	return b, nil
+}
+
+// Names returns the names of the documents in the bundle in sorted order.
+func (b *Bundle) Names() []string {
+	names := make([]string, 0, len(b.docs))
+	for name := range b.docs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Document decodes the document of the given name.
+// Each call returns a new tree, which can be modified freely.
+func (b *Bundle) Document(name string) (*Document, error) {
+	data, ok := b.docs[name]
+	if !ok {
+		return nil, fmt.Errorf("haat: document %q is not in the bundle", name)
+	}
+	n, err := DecodeBinary(data)
+	if err != nil {
+		return nil, fmt.Errorf("haat: %s: %w", name, err)
+	}
+	d, ok := n.(*Document)
+	if !ok {
+		return nil, fmt.Errorf("haat: %s: not a document", name)
+	}
+	return d, nil
+}
+
+// WriteBundle parses the HTML files in fsys matching the patterns of fs.Glob
+// and writes them in the binary form as a bundle keyed by their paths.
+func WriteBundle(w io.Writer, fsys fs.FS, patterns ...string) error {
+	var names []string
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("haat: pattern %q matches no files", pattern)
+		}
+		for _, name := range matches {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	b := []byte(bundleMagic)
+	b = binary.AppendUvarint(b, uint64(len(names)))
+	for _, name := range names {
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		doc, err := ParseHTML(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("haat: %s: %w", name, err)
+		}
+		data := AppendBinary(nil, doc)
+		b = binary.AppendUvarint(b, uint64(len(name)))
+		b = append(b, name...)
+		b = binary.AppendUvarint(b, uint64(len(data)))
+		b = append(b, data...)
+	}
+	_, err := w.Write(b)
+	return err
+}
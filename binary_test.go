@@ -0,0 +1,146 @@
+package haat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	doc, err := ParseHTML(strings.NewReader(jsonSource))
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	doc.Query("p")[0].AppendC(RawT("<i>raw</i>"))
+	doc.Query("p")[0].SetA(NewAttribute("my-attr", "1"))
+	var expected bytes.Buffer
+	if err := doc.Render(&expected); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+
+	var data bytes.Buffer
+	if err := EncodeBinary(&data, doc); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	n, err := DecodeBinary(data.Bytes())
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	doc2, ok := n.(*Document)
+	if !ok {
+		t.Fatalf("got: %T\nwant: *Document", n)
+	}
+	var actual bytes.Buffer
+	if err := doc2.Render(&actual); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	if actual.String() != expected.String() {
+		t.Errorf("got: %v\nwant: %v", actual.String(), expected.String())
+	}
+	if changes := Diff(doc, doc2, nil); len(changes) != 0 {
+		t.Errorf("got: %v\nwant: no changes", FormatChanges(changes))
+	}
+	if actual, expected := doc2.Query("p")[0].DataAtom, doc.Query("p")[0].DataAtom; actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+	if fo := doc2.Query("svg > *")[1]; fo.Data != "foreignObject" || fo.DataAtom != doc.Query("svg > *")[1].DataAtom {
+		t.Errorf("got: %v %v\nwant: foreignObject", fo.Data, fo.DataAtom)
+	}
+}
+
+func TestDecodeBinaryError(t *testing.T) {
+	data := AppendBinary(nil, E(0x100).C(T("a")))
+	if _, err := DecodeBinary(data); !errors.Is(err, ErrAtomMismatch) {
+		t.Errorf("got: %v\nwant: %v", err, ErrAtomMismatch)
+	}
+
+	data = AppendBinary(nil, dumpPage())
+	for _, n := range []int{0, 5, 20, len(data) - 1} {
+		if _, err := DecodeBinary(data[:n]); err == nil {
+			t.Errorf("%d: got: %v\nwant: error", n, err)
+		}
+	}
+	fingerprint := bytes.Clone(data)
+	fingerprint[len(binaryMagic)]++
+	if _, err := DecodeBinary(fingerprint); !errors.Is(err, ErrAtomMismatch) {
+		t.Errorf("got: %v\nwant: %v", err, ErrAtomMismatch)
+	}
+}
+
+func TestBundle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte(`<title>Index</title><p>index`)},
+		"pages/a.html":    {Data: []byte(`<title>A</title><p>a`)},
+		"pages/style.css": {Data: []byte(`p {}`)},
+	}
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, fsys, "*.html", "pages/*.html", "index.html"); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	b, err := LoadBundle(buf.Bytes())
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	if actual, expected := fmt.Sprint(b.Names()), "[index.html pages/a.html]"; actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+	doc, err := b.Document("pages/a.html")
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	if actual := doc.Query("p")[0].TextContent(); actual != "a" {
+		t.Errorf("got: %v\nwant: %v", actual, "a")
+	}
+	doc.Query("p")[0].SetText("changed")
+	doc, _ = b.Document("pages/a.html")
+	if actual := doc.Query("p")[0].TextContent(); actual != "a" {
+		t.Errorf("got: %v\nwant: a new tree for each call", actual)
+	}
+	if _, err := b.Document("b.html"); err == nil {
+		t.Errorf("got: %v\nwant: error", err)
+	}
+	if err := WriteBundle(&buf, fsys, "*.htm"); err == nil {
+		t.Errorf("got: %v\nwant: error", err)
+	}
+}
+
+// benchmarkSource returns a large page for the benchmarks.
+func benchmarkSource() string {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html><html><head><title>Bench</title><link rel="stylesheet" href="/style.css"></head><body>`)
+	for i := range 200 {
+		fmt.Fprintf(&b, `<div class="row" id="row%d"><a href="/items/%d" data-id="%d">Item %d</a>`, i, i, i, i)
+		b.WriteString(`<ul><li>one</li><li>two</li><li>three &amp; four</li></ul>`)
+		b.WriteString(`<svg viewBox="0 0 10 10"><use xlink:href="#icon"></use></svg></div>`)
+	}
+	b.WriteString(`</body></html>`)
+	return b.String()
+}
+
+func BenchmarkParseHTML(b *testing.B) {
+	src := benchmarkSource()
+	b.SetBytes(int64(len(src)))
+	for range b.N {
+		if _, err := ParseHTML(strings.NewReader(src)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeBinary(b *testing.B) {
+	src := benchmarkSource()
+	doc, err := ParseHTML(strings.NewReader(src))
+	if err != nil {
+		b.Fatal(err)
+	}
+	data := AppendBinary(nil, doc)
+	b.SetBytes(int64(len(src)))
+	for range b.N {
+		if _, err := DecodeBinary(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
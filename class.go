@@ -0,0 +1,157 @@
+package haat
+
+import (
+	"slices"
+	"strings"
+)
+
+// ClassListOptions specifies how a ClassList updates the class attribute.
+type ClassListOptions struct {
+	// KeepOrder keeps the classes in insertion order.
+	// Otherwise Set sorts the classes when it adds them to existing ones.
+	KeepOrder bool
+	// RemoveEmpty removes the class attribute when no class is left.
+	RemoveEmpty bool
+	// AttrOrder is the order of the attributes when the class attribute is set.
+	AttrOrder AttrOrder
+}
+
+// ClassList updates the class attribute of an element with the options.
+type ClassList struct {
+	ClassListOptions
+	e *Element
+}
+
+// ClassList returns the class list of the node with the given options.
+// The class methods of Element use the zero options.
+func (e *Element) ClassList(opts ClassListOptions) ClassList {
+	return ClassList{ClassListOptions: opts, e: e}
+}
+
+// defaultClassList returns the class list used by the class methods of Element.
+func (e *Element) defaultClassList() ClassList {
+	return e.ClassList(ClassListOptions{})
+}
+
+// isASCIIWhitespace reports whether c is ASCII whitespace as defined by the Infra Standard.
+func isASCIIWhitespace(c rune) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
+// splitClasses splits the given strings into class tokens by ASCII whitespace.
+func splitClasses(classes ...string) []string {
+	var tokens []string
+	for _, c := range classes {
+		tokens = append(tokens, strings.FieldsFunc(c, isASCIIWhitespace)...)
+	}
+	return tokens
+}
+
+// isClassToken reports whether the class is a valid token, which is not empty and has no ASCII whitespace.
+// DOMTokenList throws an error for the others.
+func isClassToken(class string) bool {
+	return class != "" && !strings.ContainsFunc(class, isASCIIWhitespace)
+}
+
+// uniqueClasses removes duplicated classes keeping the first appearance.
+func uniqueClasses(classes []string) []string {
+	seen := map[string]struct{}{}
+	return slices.DeleteFunc(classes, func(c string) bool {
+		if _, ok := seen[c]; ok {
+			return true
+		}
+		seen[c] = struct{}{}
+		return false
+	})
+}
+
+// set sets the class attribute to the given classes.
+func (l ClassList) set(classes []string) *Element {
+	if len(classes) == 0 && l.RemoveEmpty {
+		return l.e.RemoveAttr("class")
+	}
+	return l.e.SetAOrder(l.AttrOrder, NewAttribute("class", strings.Join(classes, " ")))
+}
+
+// Set adds the given classes to the class attribute without duplication.
+// The classes are kept in insertion order if KeepOrder is set or the node has no class yet,
+// otherwise they are sorted.
+func (l ClassList) Set(classes ...string) *Element {
+	old := l.e.Classes()
+	newClasses := slices.Concat(old, splitClasses(classes...))
+	if len(old) > 0 && !l.KeepOrder {
+		slices.Sort(newClasses)
+	}
+	return l.set(uniqueClasses(newClasses))
+}
+
+// Remove removes the given classes from the class attribute.
+func (l ClassList) Remove(classes ...string) *Element {
+	if !l.e.hasAttr("class") {
+		return l.e
+	}
+	del := splitClasses(classes...)
+	return l.set(slices.DeleteFunc(l.e.Classes(), func(c string) bool {
+		return slices.Contains(del, c)
+	}))
+}
+
+// Classes returns the classes of the node in order without duplication.
+func (e *Element) Classes() []string {
+	return uniqueClasses(splitClasses(e.GetAttr("class")))
+}
+
+// HasClass returns true if the node has the given class.
+func (e *Element) HasClass(name string) bool {
+	return slices.Contains(e.Classes(), name)
+}
+
+// ToggleClass removes the class if the node has it, otherwise adds it.
+// If force is given, the class is only added when it is true and only removed when it is false.
+// It returns true if the node has the class after the call.
+// An empty class or a class with whitespace is rejected: the node is not changed and false is returned.
+func (e *Element) ToggleClass(name string, force ...bool) bool {
+	return e.defaultClassList().Toggle(name, force...)
+}
+
+// Toggle is like Element.ToggleClass.
+func (l ClassList) Toggle(name string, force ...bool) bool {
+	if !isClassToken(name) {
+		return false
+	}
+	classes := l.e.Classes()
+	has := slices.Contains(classes, name)
+	want := !has
+	if len(force) > 0 {
+		want = force[0]
+	}
+	switch {
+	case want && !has:
+		l.set(append(classes, name))
+	case !want && has:
+		l.set(slices.DeleteFunc(classes, func(c string) bool { return c == name }))
+	}
+	return want
+}
+
+// ReplaceClass replaces the class old with new keeping its position.
+// It returns false if the node does not have the class old,
+// or if either class is empty or has whitespace, which is rejected without changing the node.
+func (e *Element) ReplaceClass(old, new string) bool {
+	return e.defaultClassList().Replace(old, new)
+}
+
+// Replace is like Element.ReplaceClass.
+func (l ClassList) Replace(old, new string) bool {
+	if !isClassToken(old) || !isClassToken(new) {
+		return false
+	}
+	classes := l.e.Classes()
+	i := slices.Index(classes, old)
+	if i < 0 {
+		return false
+	}
+	classes[i] = new
+	l.set(uniqueClasses(classes))
+	return true
+}
@@ -0,0 +1,90 @@
+package haat
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html/atom"
+)
+
+func TestClasses(t *testing.T) {
+	ht, err := ParseHTMLFragment(strings.NewReader("<p class=\" foo\tbar  foo\nbaz \">Hello</p>"), NewElement(atom.Div))
+	if err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+	p := ht[0]
+
+	expected := []string{"foo", "bar", "baz"}
+	if actual := p.Classes(); !slices.Equal(actual, expected) {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+	if !p.HasClass("bar") || p.HasClass("ba") {
+		t.Errorf("HasClass: got: %v\nwant: %v", p.Classes(), "bar")
+	}
+
+	if p.ToggleClass("bar") {
+		t.Errorf("ToggleClass(bar) = true, want false")
+	}
+	if !p.ToggleClass("qux") {
+		t.Errorf("ToggleClass(qux) = false, want true")
+	}
+	if !p.ToggleClass("qux", true) {
+		t.Errorf("ToggleClass(qux, true) = false, want true")
+	}
+	if p.ReplaceClass("nothing", "x") {
+		t.Errorf("ReplaceClass(nothing, x) = true, want false")
+	}
+	if !p.ReplaceClass("foo", "zoo") {
+		t.Errorf("ReplaceClass(foo, zoo) = false, want true")
+	}
+	if p.ToggleClass("", true) || p.ToggleClass("a b") {
+		t.Errorf("ToggleClass of invalid tokens = true, want false")
+	}
+	if p.ReplaceClass("zoo", "a b") || p.ReplaceClass("zoo", "") {
+		t.Errorf("ReplaceClass to invalid tokens = true, want false")
+	}
+
+	expected = []string{"zoo", "baz", "qux"}
+	if actual := p.Classes(); !slices.Equal(actual, expected) {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+}
+
+func TestRemoveEmptyClass(t *testing.T) {
+	p := NewElement(atom.P).SetA(A("class", "a\tb"))
+	p.RemoveClasses("a", "b")
+	if actual := p.GetAttr("class"); actual != "" || len(p.Attr) != 1 {
+		t.Errorf("got: %v\nwant: %v", p.Attr, `class=""`)
+	}
+
+	classes := p.ClassList(ClassListOptions{RemoveEmpty: true})
+	classes.Set("a")
+	classes.Remove("a")
+	if len(p.Attr) != 0 {
+		t.Errorf("got: %v\nwant: %v", p.Attr, nil)
+	}
+}
+
+func TestSetClassesOrder(t *testing.T) {
+	p := NewElement(atom.P).SetClasses("mt-2", "flex", "mt-2")
+	if actual, expected := p.GetAttr("class"), "mt-2 flex"; actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+
+	p.ClassList(ClassListOptions{KeepOrder: true}).Set("flex", "items-center p-4")
+	if actual, expected := p.GetAttr("class"), "mt-2 flex items-center p-4"; actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+
+	p.SetClasses("b")
+	if actual, expected := p.GetAttr("class"), "b flex items-center mt-2 p-4"; actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+
+	q := NewElement(atom.P).SetA(A("id", "x"))
+	q.ClassList(ClassListOptions{AttrOrder: AttrOrderSource}).Set("c")
+	if actual, expected := q.Attr[1].Key, "class"; actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/turutcrane/haat"
+)
+
+func runDump(args []string, env *env) error {
+	var opts haat.DumpOptions
+	flags := newFlagSet("dump", "[-depth n] [-text n] [-json] [-fragment] [file]", env)
+	flags.IntVar(&opts.MaxDepth, "depth", 0, "maximum depth of the nodes shown; 0 means no limit")
+	flags.IntVar(&opts.MaxText, "text", 0, "maximum characters of text shown; 0 means the default and -1 no limit")
+	flags.BoolVar(&opts.JSON, "json", false, "print the tree as JSON")
+	fragment := flags.Bool("fragment", false, "parse the file as an HTML fragment")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return errUsage
+	}
+	doc, nodes, err := parseInput(inputs(flags)[0], env, *fragment)
+	if err != nil {
+		return err
+	}
+	if !*fragment {
+		return haat.Dump(env.stdout, doc, opts)
+	}
+	for _, n := range nodes {
+		if err := haat.Dump(env.stdout, n, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
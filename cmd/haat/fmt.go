@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/turutcrane/haat"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func runFmt(args []string, env *env) error {
+	flags := newFlagSet("fmt", "[-w] [-indent string] [-fragment] [file...]", env)
+	write := flags.Bool("w", false, "write the result to the files instead of the standard output")
+	indent := flags.String("indent", "  ", "indentation of a level")
+	fragment := flags.Bool("fragment", false, "format the files as HTML fragments")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *write && flags.NArg() == 0 {
+		return errors.New("-w requires file arguments")
+	}
+	for _, name := range inputs(flags) {
+		src, err := readInput(name, env)
+		if err != nil {
+			return err
+		}
+		out, err := formatHTML(src, *indent, *fragment)
+		if err != nil {
+			return err
+		}
+		if !*write {
+			if _, err := env.stdout.Write(out); err != nil {
+				return err
+			}
+		} else if !bytes.Equal(src, out) {
+			if err := writeFile(name, out); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeFile replaces the file with the data keeping its permissions.
+// The data is written to a temporary file, which is renamed to the file,
+// so that the file is not left half written.
+func writeFile(name string, data []byte) error {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(fi.Mode().Perm()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// formatHTML returns the HTML rendered by RenderIndent.
+// The top-level nodes of a fragment are put on their own lines
+// unless it would add whitespace before a text.
+func formatHTML(src []byte, indent string, fragment bool) ([]byte, error) {
+	var buf bytes.Buffer
+	if !fragment {
+		doc, err := haat.ParseHTML(bytes.NewReader(src))
+		if err != nil {
+			return nil, err
+		}
+		if err := doc.RenderIndent(&buf, indent); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	nodes, err := haat.ParseFragment(bytes.NewReader(src), haat.E(atom.Body))
+	if err != nil {
+		return nil, err
+	}
+	for i, n := range nodes {
+		switch n := n.(type) {
+		case *haat.Element:
+			if err := n.RenderIndent(&buf, indent); err != nil {
+				return nil, err
+			}
+			if next, ok := nextText(nodes, i); ok && next != "" && !isSpace(next[0]) {
+				buf.Truncate(buf.Len() - 1)
+			}
+		case *haat.Text:
+			if strings.TrimSpace(n.Data) == "" {
+				continue
+			}
+			buf.WriteString(html.EscapeString(n.Data))
+		case *haat.Comment:
+			buf.WriteString("<!--" + n.Data + "-->\n")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// nextText returns the data of the node after nodes[i] if it is a text node.
+func nextText(nodes []haat.Node, i int) (string, bool) {
+	if i+1 < len(nodes) {
+		if t, ok := nodes[i+1].(*haat.Text); ok {
+			return t.Data, true
+		}
+	}
+	return "", false
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
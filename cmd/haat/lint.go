@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/turutcrane/haat"
+	"golang.org/x/net/html/atom"
+)
+
+func runLint(args []string, env *env) error {
+	flags := newFlagSet("lint", "[-parse=false] [-fragment] [file...]", env)
+	parse := flags.Bool("parse", true, "report the errors the parser recovers from")
+	fragment := flags.Bool("fragment", false, "lint the files as HTML fragments, such as template partials, without source positions")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	problems := 0
+	for _, name := range inputs(flags) {
+		src, err := readInput(name, env)
+		if err != nil {
+			return err
+		}
+		filename := name
+		if name == "-" {
+			filename = "<stdin>"
+		}
+		n, err := lint(env, src, filename, *parse, *fragment)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		problems += n
+	}
+	if problems > 0 {
+		return errSilent
+	}
+	return nil
+}
+
+// lint prints the problems of the document one per line and returns the number of them.
+// A fragment is parsed by ParseFragment, which records no source positions
+// nor the errors the parser recovers from.
+func lint(env *env, src []byte, filename string, parse, fragment bool) (int, error) {
+	var issues []*haat.Issue
+	var roots []*haat.Element
+	if fragment {
+		nodes, err := haat.ParseFragment(bytes.NewReader(src), haat.E(atom.Body))
+		if err != nil {
+			return 0, err
+		}
+		roots = append(roots, haat.E(atom.Body).AppendNodes(nodes...))
+	} else {
+		doc, diagnosed, err := haat.ParseHTMLDiagnose(bytes.NewReader(src), filename)
+		if err != nil {
+			return 0, err
+		}
+		defer doc.ForgetSourcePos()
+		if parse {
+			issues = diagnosed
+		}
+		roots = doc.Query("html")
+	}
+
+	var others []string
+	for _, root := range roots {
+		for _, c := range haat.BuiltinCheckers {
+			err := c(root)
+			if err == nil {
+				continue
+			}
+			found := haat.Issues(err)
+			if len(found) == 0 {
+				others = append(others, filename+": "+err.Error())
+			}
+			for _, issue := range found {
+				if issue.Pos.IsValid() {
+					issues = append(issues, issue)
+				} else {
+					others = append(others, filename+": "+issue.Message)
+				}
+			}
+		}
+	}
+	slices.SortStableFunc(issues, func(a, b *haat.Issue) int {
+		return cmp.Or(cmp.Compare(a.Pos.Line, b.Pos.Line), cmp.Compare(a.Pos.Col, b.Pos.Col))
+	})
+	lines := others
+	for _, issue := range issues {
+		lines = append(lines, issue.Error())
+	}
+	for _, l := range lines {
+		if _, err := fmt.Fprintln(env.stdout, l); err != nil {
+			return 0, err
+		}
+	}
+	return len(lines), nil
+}
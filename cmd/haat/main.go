@@ -0,0 +1,146 @@
+// Command haat is a tool for HTML templates built with haat.
+//
+// Usage:
+//
+//	haat <command> [arguments]
+//
+// The commands are:
+//
+//	query   print the elements matching a CSS selector
+//	lint    report parser errors and problems found by the built-in checkers
+//	fmt     re-render HTML with indentation
+//	dump    print the node tree
+//	togo    convert an HTML fragment to Go code building it with haat
+//
+// Run "haat <command> -h" for the arguments of a command.
+// A file argument "-" or no file argument reads the standard input.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/turutcrane/haat"
+	"golang.org/x/net/html/atom"
+)
+
+// env is the standard input and outputs of a command.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// command is a subcommand of haat.
+type command struct {
+	name  string
+	short string
+	run   func(args []string, env *env) error
+}
+
+var commands = []*command{
+	{"query", "print the elements matching a CSS selector", runQuery},
+	{"lint", "report parser errors and problems found by the built-in checkers", runLint},
+	{"fmt", "re-render HTML with indentation", runFmt},
+	{"dump", "print the node tree", runDump},
+	{"togo", "convert an HTML fragment to Go code building it with haat", runToGo},
+}
+
+// errUsage makes haat exit with status 2 after the usage is printed by the flag set.
+var errUsage = errors.New("usage")
+
+// errSilent makes haat exit with status 1 without printing an error,
+// because the command has already reported the problems.
+var errSilent = errors.New("silent error")
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: haat <command> [arguments]\n\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-8s%s\n", c.name, c.short)
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}))
+}
+
+// run runs the command in args and returns the exit status.
+func run(args []string, env *env) int {
+	if len(args) == 0 {
+		usage(env.stderr)
+		return 2
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		switch err := c.run(args[1:], env); err {
+		case nil:
+			return 0
+		case errUsage:
+			return 2
+		case errSilent:
+			return 1
+		default:
+			fmt.Fprintf(env.stderr, "haat %s: %v\n", c.name, err)
+			return 1
+		}
+	}
+	fmt.Fprintf(env.stderr, "haat: unknown command %q\n", args[0])
+	usage(env.stderr)
+	return 2
+}
+
+// newFlagSet returns the flag set of the command with the usage of its arguments.
+func newFlagSet(name, args string, env *env) *flag.FlagSet {
+	flags := flag.NewFlagSet("haat "+name, flag.ContinueOnError)
+	flags.SetOutput(env.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(env.stderr, "usage: haat %s %s\n", name, args)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses the arguments and returns errUsage if they are invalid.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	return nil
+}
+
+// inputs returns the file arguments, or "-" for the standard input if there is none.
+func inputs(flags *flag.FlagSet) []string {
+	if flags.NArg() == 0 {
+		return []string{"-"}
+	}
+	return flags.Args()
+}
+
+// parseInput parses the named file, or the standard input for "-", as a document,
+// or as a fragment in a body element if fragment is true.
+func parseInput(name string, env *env, fragment bool) (*haat.Document, []haat.Node, error) {
+	src, err := readInput(name, env)
+	if err != nil {
+		return nil, nil, err
+	}
+	if fragment {
+		nodes, err := haat.ParseFragment(bytes.NewReader(src), haat.E(atom.Body))
+		return nil, nodes, err
+	}
+	doc, err := haat.ParseHTML(bytes.NewReader(src))
+	return doc, nil, err
+}
+
+// readInput reads the named file, or the standard input for "-".
+func readInput(name string, env *env) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(env.stdin)
+	}
+	return os.ReadFile(name)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runWith runs haat with the arguments and the standard input.
+func runWith(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	status := run(args, &env{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr})
+	return status, stdout.String(), stderr.String()
+}
+
+const page = `<!DOCTYPE html><html><head><title>Hello</title></head><body>
+<ul id="list"><li>one</li><li> two  <b>2</b></li></ul><p id="list">x</p></body></html>`
+
+func TestQuery(t *testing.T) {
+	status, stdout, _ := runWith(t, page, "query", "li")
+	if expected := "<li>one</li>\n<li> two  <b>2</b></li>\n"; status != 0 || stdout != expected {
+		t.Errorf("got: %v %v\nwant: %v", status, stdout, expected)
+	}
+	status, stdout, _ = runWith(t, page, "query", "-text", "li")
+	if expected := "one\ntwo 2\n"; status != 0 || stdout != expected {
+		t.Errorf("got: %v %v\nwant: %v", status, stdout, expected)
+	}
+	status, stdout, _ = runWith(t, `<li>a</li><b>b</b>`, "query", "-fragment", "b")
+	if expected := "<b>b</b>\n"; status != 0 || stdout != expected {
+		t.Errorf("got: %v %v\nwant: %v", status, stdout, expected)
+	}
+	if status, _, _ = runWith(t, page, "query", "table"); status != 1 {
+		t.Errorf("got: %v\nwant: %v", status, 1)
+	}
+	if status, _, stderr := runWith(t, page, "query", "li["); status != 1 || stderr == "" {
+		t.Errorf("got: %v %v\nwant: %v", status, stderr, 1)
+	}
+}
+
+func TestLint(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "page.html")
+	if err := os.WriteFile(file, []byte(page+"\n<p id=\"a b\"><b><i>x</b></i><p id=\"list\"></p>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	status, stdout, _ := runWith(t, "", "lint", file)
+	expected := file + ":2:55: duplicate id: list\n" +
+		file + ":3:1: id has blank: a b\n" +
+		file + ":3:16: <i> is misnested with </b>\n" +
+		file + ":3:28: duplicate id: list\n"
+	if status != 1 || stdout != expected {
+		t.Errorf("got: %v\n%v\nwant:\n%v", status, stdout, expected)
+	}
+
+	status, stdout, _ = runWith(t, `<!DOCTYPE html><p>ok</p>`, "lint")
+	if status != 0 || stdout != "" {
+		t.Errorf("got: %v %v\nwant: %v", status, stdout, 0)
+	}
+	status, stdout, _ = runWith(t, `<!DOCTYPE html><svg><title>Chart</title><style>a{}</style></svg>`, "lint")
+	if status != 0 || stdout != "" {
+		t.Errorf("got: %v %v\nwant: %v", status, stdout, 0)
+	}
+	status, stdout, _ = runWith(t, `<b><i>x</b></i>`, "lint", "-parse=false")
+	if status != 0 || stdout != "" {
+		t.Errorf("got: %v %v\nwant: %v", status, stdout, 0)
+	}
+	status, stdout, _ = runWith(t, `<p>ok</p>`, "lint")
+	if expected := "<stdin>:1:1: missing <!DOCTYPE html>, the document renders in quirks mode\n"; status != 1 || stdout != expected {
+		t.Errorf("got: %v %v\nwant: %v", status, stdout, expected)
+	}
+
+	status, stdout, _ = runWith(t, `<p id="a">ok</p>`, "lint", "-fragment")
+	if status != 0 || stdout != "" {
+		t.Errorf("got: %v %v\nwant: %v", status, stdout, 0)
+	}
+	status, stdout, _ = runWith(t, `<li id="a"></li><li id="a"></li><li id="a b"></li><li id="a b"></li>`, "lint", "-fragment")
+	expected = "<stdin>: duplicate id: a\n" +
+		"<stdin>: duplicate id: a b\n" +
+		"<stdin>: id has blank: a b\n" +
+		"<stdin>: id has blank: a b\n"
+	if status != 1 || stdout != expected {
+		t.Errorf("got: %v\n%v\nwant:\n%v", status, stdout, expected)
+	}
+}
+
+func TestFmt(t *testing.T) {
+	status, stdout, _ := runWith(t, page, "fmt", "-indent", "\t")
+	expected := `<!DOCTYPE html>
+<html>
+	<head>
+		<title>Hello</title>
+	</head>
+	<body>
+		<ul id="list">
+			<li>one</li>
+			<li> two  <b>2</b></li>
+		</ul>
+		<p id="list">x</p>
+	</body>
+</html>
+`
+	if status != 0 || stdout != expected {
+		t.Errorf("got: %v\n%v\nwant:\n%v", status, stdout, expected)
+	}
+
+	status, stdout, _ = runWith(t, "<b>x</b>y <div><p>z</p></div><!--c-->", "fmt", "-fragment")
+	expected = "<b>x</b>y <div>\n  <p>z</p>\n</div>\n<!--c-->\n"
+	if status != 0 || stdout != expected {
+		t.Errorf("got: %v\n%v\nwant:\n%v", status, stdout, expected)
+	}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "page.html")
+	if err := os.WriteFile(file, []byte(page), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if status, _, stderr := runWith(t, "", "fmt", "-w", "-indent", "\t", file); status != 0 {
+		t.Errorf("got: %v %v\nwant: %v", status, stderr, 0)
+	}
+	if actual, _ := os.ReadFile(file); !strings.HasPrefix(string(actual), "<!DOCTYPE html>\n<html>\n\t<head>") {
+		t.Errorf("got:\n%s\nwant: formatted file", actual)
+	}
+	if fi, err := os.Stat(file); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("got: %v %v\nwant: %v", fi.Mode().Perm(), err, os.FileMode(0o600))
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("got: %v\nwant: only %v", entries, file)
+	}
+	if status, _, _ := runWith(t, page, "fmt", "-w"); status != 1 {
+		t.Errorf("got: %v\nwant: %v", status, 1)
+	}
+}
+
+func TestDump(t *testing.T) {
+	status, stdout, _ := runWith(t, `<p class="x">hello</p>`, "dump", "-fragment")
+	expected := "Element p class=\"x\"\n  Text \"hello\"\n"
+	if status != 0 || stdout != expected {
+		t.Errorf("got: %v\n%v\nwant:\n%v", status, stdout, expected)
+	}
+	status, stdout, _ = runWith(t, page, "dump", "-depth", "1")
+	expected = "Document\n  Doctype html\n  Element html\n    … 2 more\n"
+	if status != 0 || stdout != expected {
+		t.Errorf("got: %v\n%v\nwant:\n%v", status, stdout, expected)
+	}
+}
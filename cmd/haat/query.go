@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/turutcrane/haat"
+	"golang.org/x/net/html/atom"
+)
+
+func runQuery(args []string, env *env) error {
+	flags := newFlagSet("query", "[-text] [-fragment] selector [file...]", env)
+	text := flags.Bool("text", false, "print the text of the elements instead of the outer HTML")
+	fragment := flags.Bool("fragment", false, "parse the files as HTML fragments")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errUsage
+	}
+	sel, err := haat.SelectorParse(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	files := flags.Args()[1:]
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	found := false
+	for _, name := range files {
+		doc, nodes, err := parseInput(name, env, *fragment)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		var elements []*haat.Element
+		if *fragment {
+			elements = haat.E(atom.Body).AppendNodes(nodes...).QuerySelector(sel)
+		} else {
+			elements = doc.QuerySelector(sel)
+		}
+		for _, e := range elements {
+			found = true
+			s := e.InnerText()
+			if !*text {
+				if s, err = e.OuterHTML(); err != nil {
+					return err
+				}
+			}
+			if len(files) > 1 {
+				s = name + ": " + s
+			}
+			if _, err := fmt.Fprintln(env.stdout, s); err != nil {
+				return err
+			}
+		}
+	}
+	if !found {
+		return errSilent
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/turutcrane/haat"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// toGoOptions specifies the Go code generated by toGo.
+type toGoOptions struct {
+	pkg     string
+	fn      string
+	context string
+	trim    bool
+}
+
+func runToGo(args []string, env *env) error {
+	var opts toGoOptions
+	flags := newFlagSet("togo", "[-pkg name] [-func name] [-context tag] [-trim] [file]", env)
+	flags.StringVar(&opts.pkg, "pkg", "main", "package name of the generated code")
+	flags.StringVar(&opts.fn, "func", "Fragment", "name of the generated function")
+	flags.StringVar(&opts.context, "context", "body", "tag name of the context element of the fragment")
+	flags.BoolVar(&opts.trim, "trim", false, "drop whitespace-only text nodes")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return errUsage
+	}
+	src, err := readInput(inputs(flags)[0], env)
+	if err != nil {
+		return err
+	}
+	code, err := toGo(src, opts)
+	if err != nil {
+		return err
+	}
+	_, err = env.stdout.Write(code)
+	return err
+}
+
+// goWriter writes the Go expressions building the nodes.
+type goWriter struct {
+	buf     bytes.Buffer
+	trim    bool
+	useAtom bool
+}
+
+// atomName returns the name of the constant of the atom in the atom package,
+// such as AcceptCharset for "accept-charset" and ForeignObject for "foreignObject".
+func atomName(a atom.Atom) string {
+	var b strings.Builder
+	for _, w := range strings.Split(a.String(), "-") {
+		if w != "" {
+			b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+	}
+	return b.String()
+}
+
+// skip reports whether the node is dropped by the trim option.
+func (g *goWriter) skip(n *html.Node) bool {
+	return g.trim && n.Type == html.TextNode && strings.TrimSpace(n.Data) == ""
+}
+
+// element writes the constructor of the element.
+func (g *goWriter) element(n *html.Node) {
+	switch {
+	case n.Namespace == haat.NamespaceSVG:
+		fmt.Fprintf(&g.buf, "haat.NewElementNS(haat.NamespaceSVG, %q)", n.Data)
+	case n.Namespace == haat.NamespaceMathML:
+		fmt.Fprintf(&g.buf, "haat.NewElementNS(haat.NamespaceMathML, %q)", n.Data)
+	case n.Namespace != "":
+		fmt.Fprintf(&g.buf, "haat.NewElementNS(%q, %q)", n.Namespace, n.Data)
+	case n.DataAtom != 0 && n.DataAtom.String() == n.Data:
+		g.useAtom = true
+		fmt.Fprintf(&g.buf, "haat.E(atom.%s)", atomName(n.DataAtom))
+	case haat.ValidCustomElementName(n.Data) == nil:
+		fmt.Fprintf(&g.buf, "haat.CE(%q)", n.Data)
+	default:
+		fmt.Fprintf(&g.buf, "haat.NewElementNS(haat.NamespaceHTML, %q)", n.Data)
+	}
+}
+
+// attr writes the expression of the attribute.
+// A is used unless it would change the key, such as the camel case keys of SVG.
+func (g *goWriter) attr(a html.Attribute) {
+	switch {
+	case a.Namespace != "":
+		fmt.Fprintf(&g.buf, "haat.NewAttributeNS(%q, %q, %q)", a.Namespace, a.Key, a.Val)
+	case a.Key == strings.ToLower(a.Key) && !strings.Contains(a.Key, ":"):
+		fmt.Fprintf(&g.buf, "haat.A(%q, %q)", a.Key, a.Val)
+	default:
+		fmt.Fprintf(&g.buf, "haat.Attribute{Key: %q, Val: %q}", a.Key, a.Val)
+	}
+}
+
+func (g *goWriter) node(n *html.Node) error {
+	switch n.Type {
+	case html.TextNode:
+		fmt.Fprintf(&g.buf, "haat.T(%s)", strconv.Quote(n.Data))
+	case html.CommentNode:
+		fmt.Fprintf(&g.buf, "haat.NewComment(%s)", strconv.Quote(n.Data))
+	case html.ElementNode:
+		g.element(n)
+		if len(n.Attr) > 0 {
+			g.buf.WriteString(".SetA(")
+			for i, a := range n.Attr {
+				if i > 0 {
+					g.buf.WriteString(", ")
+				}
+				g.attr(a)
+			}
+			g.buf.WriteString(")")
+		}
+		if err := g.children(".AppendC(", ")", n); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported node: %v", (*haat.Element)(n))
+	}
+	return nil
+}
+
+// children writes the children of the node one per line between open and close.
+func (g *goWriter) children(open, close string, n *html.Node) error {
+	var children []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if !g.skip(c) {
+			children = append(children, c)
+		}
+	}
+	if len(children) == 0 {
+		return nil
+	}
+	g.buf.WriteString(open + "\n")
+	for _, c := range children {
+		if err := g.node(c); err != nil {
+			return err
+		}
+		g.buf.WriteString(",\n")
+	}
+	g.buf.WriteString(close)
+	return nil
+}
+
+// toGo converts the HTML fragment to a Go file with a function which builds it with haat.
+// The function returns *haat.Element if the fragment is a single element,
+// otherwise []haat.ElementChild.
+func toGo(src []byte, opts toGoOptions) ([]byte, error) {
+	if !token.IsIdentifier(opts.fn) {
+		return nil, fmt.Errorf("invalid function name: %q", opts.fn)
+	}
+	if !token.IsIdentifier(opts.pkg) {
+		return nil, fmt.Errorf("invalid package name: %q", opts.pkg)
+	}
+	a := atom.Lookup([]byte(opts.context))
+	if a == 0 {
+		return nil, fmt.Errorf("unknown context element: %q", opts.context)
+	}
+	nodes, err := haat.ParseFragment(bytes.NewReader(src), haat.E(a))
+	if err != nil {
+		return nil, err
+	}
+
+	// The parsed nodes are moved under a root to be written as its children.
+	g := &goWriter{trim: opts.trim}
+	root := &html.Node{Type: html.DocumentNode}
+	for _, n := range nodes {
+		root.AppendChild(htmlNode(n))
+	}
+	var roots []*html.Node
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if !g.skip(c) {
+			roots = append(roots, c)
+		}
+	}
+
+	var result string
+	if len(roots) == 1 && roots[0].Type == html.ElementNode {
+		result = "*haat.Element"
+		g.buf.WriteString("return ")
+		if err := g.node(roots[0]); err != nil {
+			return nil, err
+		}
+	} else {
+		result = "[]haat.ElementChild"
+		g.buf.WriteString("return []haat.ElementChild")
+		if err := g.children("{", "}", root); err != nil {
+			return nil, err
+		}
+		if len(roots) == 0 {
+			g.buf.WriteString("{}")
+		}
+	}
+
+	var code bytes.Buffer
+	fmt.Fprintf(&code, "package %s\n\nimport (\n\t\"github.com/turutcrane/haat\"\n", opts.pkg)
+	if g.useAtom {
+		code.WriteString("\t\"golang.org/x/net/html/atom\"\n")
+	}
+	fmt.Fprintf(&code, ")\n\n// %s builds the HTML fragment converted by haat togo.\nfunc %s() %s {\n", opts.fn, opts.fn, result)
+	code.Write(g.buf.Bytes())
+	code.WriteString("\n}\n")
+	return format.Source(code.Bytes())
+}
+
+// htmlNode returns the node of x/net/html of the parsed node.
+func htmlNode(n haat.Node) *html.Node {
+	switch n := n.(type) {
+	case *haat.Element:
+		return (*html.Node)(n)
+	case *haat.Text:
+		return (*html.Node)(n)
+	case *haat.Comment:
+		return (*html.Node)(n)
+	case *haat.RawText:
+		return (*html.Node)(n)
+	case *haat.Doctype:
+		return (*html.Node)(n)
+	}
+	return (*html.Node)(n.(*haat.Document))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestToGo(t *testing.T) {
+	src := `<ul class="list">
+  <li data-id="1">a &amp; b</li>
+  <x-item></x-item>
+</ul>
+<svg viewBox="0 0 1 1"><use xlink:href="#i"></use></svg>`
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"togo", "-pkg", "views", "-func", "List", "-trim"},
+		&env{stdin: strings.NewReader(src), stdout: &stdout, stderr: &stderr})
+	if status != 0 {
+		t.Fatalf("got: %v %v\nwant: %v", status, stderr.String(), 0)
+	}
+	expected := `package views
+
+import (
+	"github.com/turutcrane/haat"
+	"golang.org/x/net/html/atom"
+)
+
+// List builds the HTML fragment converted by haat togo.
+func List() []haat.ElementChild {
+	return []haat.ElementChild{
+		haat.E(atom.Ul).SetA(haat.A("class", "list")).AppendC(
+			haat.E(atom.Li).SetA(haat.A("data-id", "1")).AppendC(
+				haat.T("a & b"),
+			),
+			haat.CE("x-item"),
+		),
+		haat.NewElementNS(haat.NamespaceSVG, "svg").SetA(haat.Attribute{Key: "viewBox", Val: "0 0 1 1"}).AppendC(
+			haat.NewElementNS(haat.NamespaceSVG, "use").SetA(haat.NewAttributeNS("xlink", "href", "#i")),
+		),
+	}
+}
+`
+	if actual := stdout.String(); actual != expected {
+		t.Errorf("got:\n%v\nwant:\n%v", actual, expected)
+	}
+}
+
+func TestToGoSingleElement(t *testing.T) {
+	code, err := toGo([]byte("<tr><td>1</td><!-- c --></tr>\n"), toGoOptions{pkg: "main", fn: "Row", context: "tbody", trim: true})
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	expected := `// Row builds the HTML fragment converted by haat togo.
+func Row() *haat.Element {
+	return haat.E(atom.Tr).AppendC(
+		haat.E(atom.Td).AppendC(
+			haat.T("1"),
+		),
+		haat.NewComment(" c "),
+	)
+}
+`
+	if actual := string(code); !strings.HasSuffix(actual, expected) {
+		t.Errorf("got:\n%v\nwant:\n%v", actual, expected)
+	}
+
+	if _, err := toGo(nil, toGoOptions{pkg: "main", fn: "Row", context: "no-such"}); err == nil {
+		t.Errorf("got: %v\nwant: error", err)
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := &env{stdin: strings.NewReader(""), stdout: &stdout, stderr: &stderr}
+	if status := run(nil, e); status != 2 {
+		t.Errorf("got: %v\nwant: %v", status, 2)
+	}
+	if status := run([]string{"nothing"}, e); status != 2 || !strings.Contains(stderr.String(), `unknown command "nothing"`) {
+		t.Errorf("got: %v %v\nwant: %v", status, stderr.String(), 2)
+	}
+	if status := run([]string{"togo", "-no-such-flag"}, e); status != 2 {
+		t.Errorf("got: %v\nwant: %v", status, 2)
+	}
+}
@@ -0,0 +1,47 @@
+// Command haatc pre-compiles HTML templates into a bundle of the binary form of haat,
+// which is loaded by haat.LoadBundle much faster than parsing the HTML.
+//
+// Usage:
+//
+//	haatc [-C dir] -o file pattern...
+//
+// The patterns are matched by fs.Glob in the directory, and the documents in the bundle
+// are keyed by their paths relative to it. For example, with go generate and go:embed:
+//
+//	//go:generate go run github.com/turutcrane/haat/cmd/haatc -C templates -o templates.haatb *.html
+//	//go:embed templates.haatb
+//	var templates []byte
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/turutcrane/haat"
+)
+
+func main() {
+	dir := flag.String("C", ".", "directory of the templates")
+	out := flag.String("o", "", "output file")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: haatc [-C dir] -o file pattern...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *out == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var buf bytes.Buffer
+	if err := haat.WriteBundle(&buf, os.DirFS(*dir), flag.Args()...); err != nil {
+		fmt.Fprintln(os.Stderr, "haatc:", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "haatc:", err)
+		os.Exit(1)
+	}
+}
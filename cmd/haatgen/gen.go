@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/turutcrane/haat"
+)
+
+// field is a typed field of the generated struct.
+type field struct {
+	Name string
+	// Attr is "id" or haat.RefAttr, and Value is its value.
+	Attr  string
+	Value string
+}
+
+// Method returns the method of haat.Document which finds the element.
+func (f field) Method() string {
+	if f.Attr == "id" {
+		return "ElementByID"
+	}
+	return "ElementByRef"
+}
+
+// goName makes an exported Go identifier from the words in s,
+// such as PkgName from "pkg-name" or "pkgName".
+func goName(s string) (string, error) {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, w := range words {
+		r, size := utf8.DecodeRuneInString(w)
+		b.WriteRune(unicode.ToUpper(r))
+		b.WriteString(w[size:])
+	}
+	name := b.String()
+	if !token.IsIdentifier(name) || !token.IsExported(name) {
+		return "", fmt.Errorf("can not make a Go name from %q", s)
+	}
+	return name, nil
+}
+
+// lowerFirst returns s with its first letter lowercased.
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+// fields returns the fields of the elements with an id or a data-haat-ref attribute in document order.
+func fields(doc *haat.Document) ([]field, error) {
+	var fs []field
+	names := map[string]string{"Document": "the document", "Load": "the method"}
+	values := map[string]bool{}
+	for _, e := range doc.Query("*") {
+		var f field
+		switch {
+		case hasAttr(e, haat.RefAttr):
+			f = field{Attr: haat.RefAttr, Value: e.GetAttr(haat.RefAttr)}
+		case hasAttr(e, "id"):
+			f = field{Attr: "id", Value: e.ID()}
+		default:
+			continue
+		}
+		desc := fmt.Sprintf("%s %q", f.Attr, f.Value)
+		if values[desc] {
+			return nil, fmt.Errorf("duplicate %s", desc)
+		}
+		values[desc] = true
+
+		name, err := goName(f.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", desc, err)
+		}
+		if prev, ok := names[name]; ok {
+			return nil, fmt.Errorf("%s: field %s is already used for %s", desc, name, prev)
+		}
+		names[name] = desc
+		f.Name = name
+		fs = append(fs, f)
+	}
+	return fs, nil
+}
+
+func hasAttr(e *haat.Element, key string) bool {
+	for _, a := range e.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+var codeTemplate = template.Must(template.New("code").Parse(`// Code generated by haatgen from {{.Path}}; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/turutcrane/haat"
+)
+
+//go:embed {{.Path}}
+var {{.Var}}HTML string
+
+var {{.Var}}Template = sync.OnceValues(func() (*haat.Document, error) {
+	return haat.ParseHTML(strings.NewReader({{.Var}}HTML))
+})
+
+// {{.Type}} is the template {{.Path}} with its elements named by id or {{.RefAttr}}.
+type {{.Type}} struct {
+	Document *haat.Document
+{{range .Fields}}
+	// {{.Name}} is the element with {{.Attr}} {{printf "%q" .Value}}.
+	{{.Name}} *haat.Element
+{{- end}}
+}
+
+// Load clones the parsed template and sets the elements.
+func (t *{{.Type}}) Load() error {
+	doc, err := {{.Var}}Template()
+	if err != nil {
+		return fmt.Errorf("%s: %w", {{printf "%q" .Path}}, err)
+	}
+	t.Document = doc.Clone()
+{{- range .Fields}}
+	if t.{{.Name}} = t.Document.{{.Method}}({{printf "%q" .Value}}); t.{{.Name}} == nil {
+		return fmt.Errorf("%s: no element with %s %q", {{printf "%q" $.Path}}, {{printf "%q" .Attr}}, {{printf "%q" .Value}})
+	}
+{{- end}}
+	return nil
+}
+`))
+
+// identifiers returns the package level identifiers generated for the template with the name:
+// the type and the variables of the embedded source and the parsed template.
+func identifiers(name string) ([]string, error) {
+	typ, err := goName(name)
+	if err != nil {
+		return nil, fmt.Errorf("type name: %w", err)
+	}
+	v := lowerFirst(typ)
+	return []string{typ, v + "HTML", v + "Template"}, nil
+}
+
+// generate returns the formatted Go code of the template at path relative to the output directory.
+func generate(pkg, path, name string, src []byte) ([]byte, error) {
+	doc, err := haat.ParseHTML(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	fs, err := fields(doc)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := identifiers(name)
+	if err != nil {
+		return nil, err
+	}
+	typ := ids[0]
+	if strings.ContainsAny(path, " \"`") {
+		return nil, fmt.Errorf("path %q can not be embedded", path)
+	}
+
+	var buf bytes.Buffer
+	err = codeTemplate.Execute(&buf, map[string]any{
+		"Package": pkg,
+		"Path":    path,
+		"Type":    typ,
+		"Var":     lowerFirst(typ),
+		"RefAttr": haat.RefAttr,
+		"Fields":  fs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
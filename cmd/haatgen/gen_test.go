@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoName(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"pkgname", "Pkgname"},
+		{"pkgName", "PkgName"},
+		{"pkg-name", "PkgName"},
+		{"user_list.item", "UserListItem"},
+		{"émoji", "Émoji"},
+		{"1st", ""},
+		{"--", ""},
+	}
+	for _, tt := range tests {
+		actual, err := goName(tt.s)
+		if actual != tt.want || (err != nil) != (tt.want == "") {
+			t.Errorf("%s: got: %v %v\nwant: %v", tt.s, actual, err, tt.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	src := `<!DOCTYPE html><title>T</title><span id="pkg-name"></span><ul data-haat-ref="items" id="list"></ul>`
+	if err := os.MkdirAll(filepath.Join(dir, "templates"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tmpl := filepath.Join(dir, "templates", "user-page.html")
+	if err := os.WriteFile(tmpl, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run("web", dir, []string{tmpl}); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	code, err := os.ReadFile(filepath.Join(dir, "user-page_haat.go"))
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	for _, want := range []string{
+		"package web\n",
+		"//go:embed templates/user-page.html\nvar userPageHTML string\n",
+		"type UserPage struct {\n",
+		"\tPkgName *haat.Element\n",
+		"\tItems *haat.Element\n",
+		`if t.PkgName = t.Document.ElementByID("pkg-name"); t.PkgName == nil {`,
+		`if t.Items = t.Document.ElementByRef("items"); t.Items == nil {`,
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("got:\n%s\nwant: %q", code, want)
+		}
+	}
+
+	other := filepath.Join(dir, "templates", "user_page.html")
+	if err := os.WriteFile(other, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expected := tmpl + " and " + other + " both declare UserPage"
+	if err := run("web", dir, []string{tmpl, other}); err == nil || err.Error() != expected {
+		t.Errorf("got: %v\nwant: %v", err, expected)
+	}
+
+	if err := run("web", filepath.Join(dir, "templates"), []string{filepath.Join(dir, "page.html")}); err == nil {
+		t.Errorf("got: %v\nwant: error for a template outside the output directory", err)
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`<p id="a"></p><p id="a"></p>`, `duplicate id "a"`},
+		{`<p id="a-b"></p><p id="aB"></p>`, `id "aB": field AB is already used for id "a-b"`},
+		{`<p data-haat-ref="document"></p>`, `data-haat-ref "document": field Document is already used for the document`},
+		{`<p id="load"></p>`, `id "load": field Load is already used for the method`},
+		{`<p id="9"></p>`, `id "9": can not make a Go name from "9"`},
+	}
+	for _, tt := range tests {
+		if _, err := generate("web", "page.html", "page", []byte(tt.src)); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: got: %v\nwant: %v", tt.src, err, tt.want)
+		}
+	}
+}
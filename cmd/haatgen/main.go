@@ -0,0 +1,91 @@
+// Command haatgen generates Go code which loads HTML templates with typed fields
+// for their named elements, so that a template change which removes or renames
+// an element breaks the build instead of a selector failing at run time.
+//
+// Usage:
+//
+//	haatgen [-pkg name] [-o dir] template.html...
+//
+// For each template, such as page.html, haatgen writes page_haat.go to the output directory.
+// The file embeds the template with go:embed, parses it once and defines a struct
+// Page with a Load method, which clones the parsed document and sets a *haat.Element field
+// for every element with an id or a data-haat-ref attribute. The field name is
+// made from the value of data-haat-ref if present, otherwise from the id,
+// such as PkgName for "pkg-name" or "pkgName".
+//
+// The templates must be in the output directory or its subdirectories.
+// The package name defaults to $GOPACKAGE, which is set by go generate:
+//
+//	//go:generate go run github.com/turutcrane/haat/cmd/haatgen page.html
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package name of the generated code (default $GOPACKAGE)")
+	outDir := flag.String("o", ".", "output directory")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: haatgen [-pkg name] [-o dir] template.html...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *pkg == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(*pkg, *outDir, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "haatgen:", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the Go file of each template.
+func run(pkg, outDir string, templates []string) error {
+	outputs := map[string]string{}
+	declared := map[string]string{}
+	for _, tmpl := range templates {
+		rel, err := filepath.Rel(outDir, tmpl)
+		if err != nil {
+			return err
+		}
+		if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%s: template must be in the output directory %s for go:embed", tmpl, outDir)
+		}
+		base := filepath.Base(tmpl)
+		name := strings.TrimSuffix(base, filepath.Ext(base))
+		out := filepath.Join(outDir, name+"_haat.go")
+		if prev, ok := outputs[out]; ok {
+			return fmt.Errorf("%s and %s generate the same file %s", prev, tmpl, out)
+		}
+		outputs[out] = tmpl
+		ids, err := identifiers(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", tmpl, err)
+		}
+		for _, id := range ids {
+			if prev, ok := declared[id]; ok {
+				return fmt.Errorf("%s and %s both declare %s", prev, tmpl, id)
+			}
+			declared[id] = tmpl
+		}
+
+		src, err := os.ReadFile(tmpl)
+		if err != nil {
+			return err
+		}
+		code, err := generate(pkg, filepath.ToSlash(rel), name, src)
+		if err != nil {
+			return fmt.Errorf("%s: %w", tmpl, err)
+		}
+		if err := os.WriteFile(out, code, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package haat
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// TextContent returns the concatenated text of the descendant text nodes.
+// Comments and raw text nodes are skipped.
+func (e *Element) TextContent() string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch c.Type {
+			case html.TextNode:
+				b.WriteString(c.Data)
+			case html.ElementNode:
+				walk(c)
+			}
+		}
+	}
+	walk((*html.Node)(e))
+	return b.String()
+}
+
+// innerTextBlocks are the elements separated from the surrounding text in InnerText.
+var innerTextBlocks = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Br: true, atom.Dd: true, atom.Div: true, atom.Dl: true, atom.Dt: true,
+	atom.Fieldset: true, atom.Figcaption: true, atom.Figure: true, atom.Footer: true,
+	atom.Form: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true,
+	atom.H5: true, atom.H6: true, atom.Header: true, atom.Hr: true, atom.Li: true,
+	atom.Main: true, atom.Nav: true, atom.Ol: true, atom.Option: true, atom.P: true,
+	atom.Pre: true, atom.Section: true, atom.Table: true, atom.Td: true, atom.Th: true,
+	atom.Tr: true, atom.Ul: true,
+}
+
+// InnerText returns the text of the node with whitespace collapsed like the rendered text.
+// The contents of script, style, template and noscript elements are skipped
+// and block elements are separated by a space.
+func (e *Element) InnerText() string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch c.Type {
+			case html.TextNode:
+				b.WriteString(c.Data)
+			case html.ElementNode:
+				switch c.DataAtom {
+				case atom.Script, atom.Style, atom.Template, atom.Noscript:
+					continue
+				}
+				if innerTextBlocks[c.DataAtom] {
+					b.WriteByte(' ')
+				}
+				walk(c)
+				if innerTextBlocks[c.DataAtom] {
+					b.WriteByte(' ')
+				}
+			}
+		}
+	}
+	walk((*html.Node)(e))
+	return strings.Join(strings.FieldsFunc(b.String(), isASCIIWhitespace), " ")
+}
+
+// InnerHTML returns the HTML serialization of the children of the node.
+func (e *Element) InnerHTML() (string, error) {
+	var buf bytes.Buffer
+	for c := e.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&buf, c); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+// OuterHTML returns the HTML serialization of the node.
+func (e *Element) OuterHTML() (string, error) {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, (*html.Node)(e)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// SetInnerHTML replaces the children of the node by the parsed HTML fragment.
+// The fragment is parsed with the node as context, so that e.g. <tr> is kept in <tbody>.
+func (e *Element) SetInnerHTML(s string) error {
+	nodes, err := ParseFragmentString(s, e)
+	if err != nil {
+		return err
+	}
+	e.ClearContents().AppendNodes(nodes...)
+	return nil
+}
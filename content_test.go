@@ -0,0 +1,52 @@
+package haat
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html/atom"
+)
+
+func TestTextContent(t *testing.T) {
+	ht, err := ParseHTMLFragment(strings.NewReader("<div>Hello <!-- note --><b>haat</b>\n<script>x()</script><p>  two\n words</p></div>"), NewElement(atom.Body))
+	if err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+	div := ht[0]
+
+	expected := "Hello haat\nx()  two\n words"
+	if actual := div.TextContent(); actual != expected {
+		t.Errorf("got: %q\nwant: %q", actual, expected)
+	}
+	expected = "Hello haat two words"
+	if actual := div.InnerText(); actual != expected {
+		t.Errorf("got: %q\nwant: %q", actual, expected)
+	}
+}
+
+func TestInnerHTML(t *testing.T) {
+	table := NewElement(atom.Table).SetA(A("id", "t"))
+	if err := table.SetInnerHTML(`<tr><td>a &amp; b</td></tr>`); err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+
+	expected := `<tbody><tr><td>a &amp; b</td></tr></tbody>`
+	actual, err := table.InnerHTML()
+	if err != nil || actual != expected {
+		t.Errorf("got: %v, %v\nwant: %v", actual, err, expected)
+	}
+
+	expected = `<table id="t">` + expected + `</table>`
+	actual, err = table.OuterHTML()
+	if err != nil || actual != expected {
+		t.Errorf("got: %v, %v\nwant: %v", actual, err, expected)
+	}
+
+	sel := NewElement(atom.Select)
+	if err := sel.SetInnerHTML(`<option>1<div>x</div><option>2`); err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+	if n := len(sel.Query("option")); n != 2 {
+		t.Errorf("got: %v\nwant: %v", n, 2)
+	}
+}
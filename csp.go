@@ -0,0 +1,219 @@
+package haat
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// CSPMode is how ApplyCSP allows the inline scripts and styles.
+type CSPMode int
+
+const (
+	// CSPNonce stamps a nonce attribute on every script and style element.
+	CSPNonce CSPMode = iota
+	// CSPHash computes the sha256 hashes of the inline scripts and styles.
+	CSPHash
+)
+
+// CSPOptions specifies the options of ApplyCSP.
+type CSPOptions struct {
+	Mode CSPMode
+	// Nonce is the nonce for CSPNonce. A new one is generated by NewNonce if it is empty.
+	// It must be a base64 or base64url value, so that it can not inject directives.
+	Nonce string
+	// ScriptSrc and StyleSrc are the sources allowed besides the nonce or hashes,
+	// such as "'self'". Nil means "'self'".
+	ScriptSrc []string
+	StyleSrc  []string
+	// Directives are added to the policy as is, such as "default-src 'self'".
+	Directives []string
+}
+
+// CSPResult is the result of ApplyCSP.
+type CSPResult struct {
+	// Header is the value of the Content-Security-Policy header.
+	Header string
+	// Nonce is the nonce stamped in CSPNonce mode.
+	Nonce string
+	// Issues are the event handler attributes and javascript: URLs, which the policy blocks.
+	Issues []*Issue
+}
+
+// NewNonce returns a new random nonce for a Content-Security-Policy.
+func NewNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// IsValidNonce reports whether the nonce matches the base64-value of the CSP grammar,
+// which allows the characters of both base64 and base64url followed by at most two "=",
+// so that it can not inject directives into the policy or break the nonce attribute.
+func IsValidNonce(nonce string) bool {
+	v := strings.TrimRight(nonce, "=")
+	if v == "" || len(nonce)-len(v) > 2 {
+		return false
+	}
+	for _, c := range []byte(v) {
+		if !('A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '+' || c == '/' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// urlAttrs are the attributes whose values are URLs which may have the javascript: scheme.
+var urlAttrs = map[string]bool{
+	"action": true, "background": true, "cite": true, "data": true, "formaction": true,
+	"href": true, "poster": true, "src": true, "xlink:href": true,
+}
+
+// isJavaScriptURL reports whether the URL has the javascript: scheme
+// after the whitespace and control characters ignored by the URL parser are removed.
+func isJavaScriptURL(u string) bool {
+	u = strings.TrimLeftFunc(u, func(r rune) bool { return r <= ' ' })
+	u = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, u)
+	return len(u) >= len("javascript:") && lower(u[:len("javascript:")]) == "javascript:"
+}
+
+// cspIssues returns the event handler attributes and javascript: URLs of the element.
+func cspIssues(e *Element) []*Issue {
+	var issues []*Issue
+	for _, a := range e.Attr {
+		name := qualifiedName(a)
+		switch {
+		case a.Namespace == "" && strings.HasPrefix(lower(a.Key), "on"):
+			issues = append(issues, NewIssue(e, "event handler attribute %s on <%s> is blocked by Content-Security-Policy", name, e.Data))
+		case urlAttrs[name] && isJavaScriptURL(a.Val):
+			issues = append(issues, NewIssue(e, "javascript: URL in %s on <%s> is blocked by Content-Security-Policy", name, e.Data))
+		}
+	}
+	return issues
+}
+
+// CSPCheck checks if the node has event handler attributes or javascript: URLs,
+// which a strict Content-Security-Policy blocks.
+func CSPCheck(e *Element) error {
+	var issues []*Issue
+	for _, c := range append([]*Element{e}, e.Query("*")...) {
+		issues = append(issues, cspIssues(c)...)
+	}
+	return joinIssues(issues)
+}
+
+// cspKind returns "script" or "style" for the script and style elements of HTML and SVG,
+// which the policy applies to, and "" for the others.
+func cspKind(e *Element) string {
+	if e.Namespace != "" && e.Namespace != NamespaceSVG {
+		return ""
+	}
+	switch e.Data {
+	case "script", "style":
+		return e.Data
+	}
+	return ""
+}
+
+// isExternalScript reports whether the script element loads its source from a URL.
+func isExternalScript(e *Element) bool {
+	if e.Namespace == NamespaceSVG {
+		return e.hasAttr("href") || e.hasAttr("xlink:href")
+	}
+	return e.hasAttr("src")
+}
+
+// scriptText returns the text of the script or style element as it is rendered.
+func scriptText(e *Element) string {
+	var b strings.Builder
+	for c := e.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode || c.Type == html.RawNode {
+			b.WriteString(c.Data)
+		}
+	}
+	return b.String()
+}
+
+// sha256Source returns the CSP source of the hash of the text.
+func sha256Source(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+}
+
+// ApplyCSP prepares the document for a strict Content-Security-Policy.
+// In CSPNonce mode, it stamps the nonce on every script and style element;
+// in CSPHash mode, it computes the hashes of the inline scripts and styles.
+// It returns the value of the Content-Security-Policy header and
+// reports the event handler attributes and javascript: URLs, which the policy blocks.
+func (d *Document) ApplyCSP(opts CSPOptions) (*CSPResult, error) {
+	result := &CSPResult{}
+	scriptSrc := opts.ScriptSrc
+	if scriptSrc == nil {
+		scriptSrc = []string{"'self'"}
+	}
+	styleSrc := opts.StyleSrc
+	if styleSrc == nil {
+		styleSrc = []string{"'self'"}
+	}
+	scriptSrc = slices.Clone(scriptSrc)
+	styleSrc = slices.Clone(styleSrc)
+
+	if opts.Mode == CSPNonce {
+		result.Nonce = opts.Nonce
+		if result.Nonce == "" {
+			nonce, err := NewNonce()
+			if err != nil {
+				return nil, err
+			}
+			result.Nonce = nonce
+		} else if !IsValidNonce(result.Nonce) {
+			return nil, fmt.Errorf("invalid CSP nonce: %q", result.Nonce)
+		}
+		scriptSrc = append(scriptSrc, "'nonce-"+result.Nonce+"'")
+		styleSrc = append(styleSrc, "'nonce-"+result.Nonce+"'")
+	}
+
+	for _, e := range d.Query("*") {
+		result.Issues = append(result.Issues, cspIssues(e)...)
+		kind := cspKind(e)
+		if kind == "" {
+			continue
+		}
+		switch opts.Mode {
+		case CSPNonce:
+			e.SetA(NewAttribute("nonce", result.Nonce))
+		case CSPHash:
+			if kind == "script" && isExternalScript(e) {
+				continue
+			}
+			src := sha256Source(scriptText(e))
+			if kind == "script" {
+				if !slices.Contains(scriptSrc, src) {
+					scriptSrc = append(scriptSrc, src)
+				}
+			} else if !slices.Contains(styleSrc, src) {
+				styleSrc = append(styleSrc, src)
+			}
+		}
+	}
+
+	directives := []string{
+		"script-src " + strings.Join(scriptSrc, " "),
+		"style-src " + strings.Join(styleSrc, " "),
+	}
+	result.Header = strings.Join(append(directives, opts.Directives...), "; ")
+	return result, nil
+}
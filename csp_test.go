@@ -0,0 +1,134 @@
+package haat
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const cspSource = `<!DOCTYPE html><html><head><style>p { color: red }</style><script src="/app.js"></script></head>
+<body onload="init()"><a href=" java	script:alert(1)">x</a><a href="/ok">ok</a>
+<script>alert(1)</script><script>alert(1)</script>
+<svg><a xlink:href="javascript:void(0)"></a></svg></body></html>`
+
+func TestApplyCSPNonce(t *testing.T) {
+	doc, err := ParseHTML(strings.NewReader(cspSource))
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	result, err := doc.ApplyCSP(CSPOptions{Nonce: "abc", Directives: []string{"object-src 'none'"}})
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	expected := "script-src 'self' 'nonce-abc'; style-src 'self' 'nonce-abc'; object-src 'none'"
+	if result.Header != expected {
+		t.Errorf("got: %v\nwant: %v", result.Header, expected)
+	}
+	for _, e := range doc.Query("script, style") {
+		if actual := e.GetAttr("nonce"); actual != "abc" {
+			t.Errorf("got: %v\nwant: %v", actual, "abc")
+		}
+	}
+
+	var messages []string
+	for _, issue := range result.Issues {
+		messages = append(messages, issue.Message)
+	}
+	expectedMessages := []string{
+		"event handler attribute onload on <body> is blocked by Content-Security-Policy",
+		"javascript: URL in href on <a> is blocked by Content-Security-Policy",
+		"javascript: URL in xlink:href on <a> is blocked by Content-Security-Policy",
+	}
+	if strings.Join(messages, "\n") != strings.Join(expectedMessages, "\n") {
+		t.Errorf("got: %v\nwant: %v", messages, expectedMessages)
+	}
+
+	result, err = doc.ApplyCSP(CSPOptions{})
+	if err != nil || len(result.Nonce) != 24 || doc.Query("script")[0].GetAttr("nonce") != result.Nonce {
+		t.Errorf("got: %v %v\nwant: a generated nonce", result.Nonce, err)
+	}
+
+	for _, nonce := range []string{"x'; script-src *", "a b", "abc===", "=="} {
+		if _, err := doc.ApplyCSP(CSPOptions{Nonce: nonce}); err == nil {
+			t.Errorf("got: %v\nwant: error for %q", err, nonce)
+		}
+	}
+	if _, err := doc.ApplyCSP(CSPOptions{Nonce: "a-b_c+d/e=="}); err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+}
+
+func TestApplyCSPHash(t *testing.T) {
+	doc, err := ParseHTML(strings.NewReader(cspSource))
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	result, err := doc.ApplyCSP(CSPOptions{Mode: CSPHash, ScriptSrc: []string{"'strict-dynamic'"}})
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	expected := "script-src 'strict-dynamic' 'sha256-bhHHL3z2vDgxUt0W3dWQOrprscmda2Y5pLsLg4GF+pI='; " +
+		"style-src 'self' 'sha256-ngewhhP73WDIbgwseeu52VAAJgKdGUsu1IUQQsAm8m4='"
+	if result.Header != expected {
+		t.Errorf("got: %v\nwant: %v", result.Header, expected)
+	}
+	if result.Nonce != "" || len(doc.Query("[nonce]")) != 0 {
+		t.Errorf("got: %v\nwant: no nonce", result.Nonce)
+	}
+	if len(result.Issues) != 3 {
+		t.Errorf("got: %v\nwant: 3 issues", result.Issues)
+	}
+}
+
+func TestApplyCSPSVG(t *testing.T) {
+	src := `<!DOCTYPE html><svg><style>rect { fill: red }</style><script>go()</script><script href="/chart.js"></script></svg>`
+	doc, err := ParseHTML(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	if _, err := doc.ApplyCSP(CSPOptions{Nonce: "abc"}); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	if actual := len(doc.Query("[nonce=abc]")); actual != 3 {
+		t.Errorf("got: %v\nwant: %v", actual, 3)
+	}
+
+	doc, err = ParseHTML(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	result, err := doc.ApplyCSP(CSPOptions{Mode: CSPHash})
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	expected := "script-src 'self' " + sha256Source("go()") + "; style-src 'self' " + sha256Source("rect { fill: red }")
+	if result.Header != expected {
+		t.Errorf("got: %v\nwant: %v", result.Header, expected)
+	}
+}
+
+func TestCSPCheck(t *testing.T) {
+	doc, err := ParseHTMLWithSourcePos(strings.NewReader(cspSource), "page.html")
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	defer doc.ForgetSourcePos()
+	var buf bytes.Buffer
+	err = doc.Render(&buf, CSPCheck)
+	var issue *Issue
+	if !errors.As(err, &issue) {
+		t.Fatalf("got: %v\nwant: *Issue", err)
+	}
+	expected := "page.html:2:1: event handler attribute onload on <body> is blocked by Content-Security-Policy"
+	if issue.Error() != expected {
+		t.Errorf("got: %v\nwant: %v", issue.Error(), expected)
+	}
+	if actual := len(Issues(err)); actual != 3 {
+		t.Errorf("got: %v\nwant: %v", actual, 3)
+	}
+
+	if err := CSPCheck(doc.Query("head")[0]); err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+}
@@ -0,0 +1,121 @@
+package haat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// dataAttrName converts the camelCase dataset key to the data-* attribute name.
+func dataAttrName(key string) (string, error) {
+	var b strings.Builder
+	b.WriteString("data-")
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c == '-' && i+1 < len(key) && 'a' <= key[i+1] && key[i+1] <= 'z':
+			return "", fmt.Errorf("invalid dataset key: %q", key)
+		case 'A' <= c && c <= 'Z':
+			b.WriteByte('-')
+			b.WriteByte(c + 'a' - 'A')
+		case c <= ' ' || c == '"' || c == '\'' || c == '/' || c == '=' || c == '>' || c == 0x7f:
+			return "", fmt.Errorf("invalid dataset key: %q", key)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// datasetKey converts the data-* attribute name to the camelCase dataset key.
+func datasetKey(name string) (string, bool) {
+	rest, ok := strings.CutPrefix(name, "data-")
+	if !ok || rest == "" {
+		return "", false
+	}
+	var b strings.Builder
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		if c == '-' && i+1 < len(rest) && 'a' <= rest[i+1] && rest[i+1] <= 'z' {
+			b.WriteByte(rest[i+1] - 'a' + 'A')
+			i++
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), true
+}
+
+// Dataset returns the data-* attributes of the node keyed by camelCase names like the DOM dataset.
+func (e *Element) Dataset() map[string]string {
+	dataset := map[string]string{}
+	for _, a := range e.Attr {
+		if a.Namespace != "" {
+			continue
+		}
+		if key, ok := datasetKey(a.Key); ok {
+			dataset[key] = a.Val
+		}
+	}
+	return dataset
+}
+
+// GetData returns the value of the data-* attribute for the camelCase key.
+// It is the Data(key) getter of the DOM dataset; the method cannot be named Data
+// because Element already has the Data field of html.Node holding the tag name.
+func (e *Element) GetData(key string) string {
+	name, err := dataAttrName(key)
+	if err != nil {
+		return ""
+	}
+	return e.GetAttr(name)
+}
+
+// SetData sets the data-* attribute for the camelCase key.
+// A string value is set as it is and other values are encoded as JSON.
+func (e *Element) SetData(key string, value any) error {
+	name, err := dataAttrName(key)
+	if err != nil {
+		return err
+	}
+	s, ok := value.(string)
+	if !ok {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		s = string(b)
+	}
+	e.SetA(NewAttribute(name, s))
+	return nil
+}
+
+// RemoveData removes the data-* attribute for the camelCase key.
+func (e *Element) RemoveData(key string) *Element {
+	name, err := dataAttrName(key)
+	if err != nil {
+		return e
+	}
+	return e.RemoveAttr(name)
+}
+
+// DataInto decodes the data-* attribute for the camelCase key into v.
+// If v is a *string, the value is stored as it is, otherwise it is decoded as JSON.
+func (e *Element) DataInto(key string, v any) error {
+	name, err := dataAttrName(key)
+	if err != nil {
+		return err
+	}
+	if !e.hasAttr(name) {
+		return fmt.Errorf("data attribute not found: %s", name)
+	}
+	val := e.GetAttr(name)
+	if s, ok := v.(*string); ok {
+		*s = val
+		return nil
+	}
+	if err := json.Unmarshal([]byte(val), v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package haat
+
+import (
+	"maps"
+	"testing"
+
+	"golang.org/x/net/html/atom"
+)
+
+func TestDataset(t *testing.T) {
+	type config struct {
+		Page  int      `json:"page"`
+		Items []string `json:"items"`
+	}
+
+	div := NewElement(atom.Div).SetA(A("data-widget-id", "w1"), A("id", "x"))
+	if err := div.SetData("pageConfig", config{Page: 2, Items: []string{"a", "<b>"}}); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	if err := div.SetData("enabled", true); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	if err := div.SetData("bad-key", 1); err == nil {
+		t.Errorf("got: %v\nwant: error", err)
+	}
+
+	expected := `{"page":2,"items":["a","\u003cb\u003e"]}`
+	if actual := div.GetAttr("data-page-config"); actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+	if actual := div.GetData("widgetId"); actual != "w1" {
+		t.Errorf("got: %v\nwant: %v", actual, "w1")
+	}
+
+	expectedSet := map[string]string{"widgetId": "w1", "pageConfig": expected, "enabled": "true"}
+	if actual := div.Dataset(); !maps.Equal(actual, expectedSet) {
+		t.Errorf("got: %v\nwant: %v", actual, expectedSet)
+	}
+
+	var c config
+	if err := div.DataInto("pageConfig", &c); err != nil || c.Page != 2 || len(c.Items) != 2 {
+		t.Errorf("got: %v, %v\nwant: %v", c, err, "page 2")
+	}
+	var s string
+	if err := div.DataInto("widgetId", &s); err != nil || s != "w1" {
+		t.Errorf("got: %v, %v\nwant: %v", s, err, "w1")
+	}
+	if err := div.DataInto("missing", &s); err == nil {
+		t.Errorf("got: %v\nwant: error", err)
+	}
+
+	div.RemoveData("widgetId").RemoveData("pageConfig").RemoveData("enabled")
+	if len(div.Attr) != 1 {
+		t.Errorf("got: %v\nwant: %v", div.Attr, "id")
+	}
+
+	bare := NewElement(atom.Div).SetA(A("data-", "x"), A("data-a", "1"))
+	if actual, expected := bare.Dataset(), map[string]string{"a": "1"}; !maps.Equal(actual, expected) {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+}
@@ -0,0 +1,404 @@
+package haat
+
+import (
+	"io"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// voidTags are the elements which have no end tag.
+var voidTags = map[string]bool{
+	"area": true, "base": true, "basefont": true, "bgsound": true, "br": true, "col": true,
+	"embed": true, "frame": true, "hr": true, "img": true, "input": true, "keygen": true,
+	"link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// optionalEndTags are the elements whose end tag may be omitted.
+var optionalEndTags = map[string]bool{
+	"html": true, "head": true, "body": true, "p": true, "li": true, "dt": true, "dd": true,
+	"option": true, "optgroup": true, "tr": true, "td": true, "th": true, "thead": true,
+	"tbody": true, "tfoot": true, "colgroup": true, "caption": true,
+	"rb": true, "rt": true, "rp": true, "rtc": true,
+}
+
+// formattingTags are the formatting elements which the parser reconstructs when misnested.
+var formattingTags = map[string]bool{
+	"a": true, "b": true, "big": true, "code": true, "em": true, "font": true, "i": true,
+	"nobr": true, "s": true, "small": true, "strike": true, "strong": true, "tt": true, "u": true,
+}
+
+// tableContexts are the elements in which text is foster parented.
+var tableContexts = map[string]bool{
+	"table": true, "tbody": true, "thead": true, "tfoot": true, "tr": true,
+}
+
+// quirksPublicIDPrefixes are the public identifiers of the doctype
+// which make a document render in quirks mode. They are in lower case.
+var quirksPublicIDPrefixes = []string{
+	"+//silmaril//dtd html pro v0r11 19970101//",
+	"-//as//dtd html 3.0 aswedit + extensions//",
+	"-//advasoft ltd//dtd html 3.0 aswedit + extensions//",
+	"-//ietf//dtd html 2.0 level 1//",
+	"-//ietf//dtd html 2.0 level 2//",
+	"-//ietf//dtd html 2.0 strict level 1//",
+	"-//ietf//dtd html 2.0 strict level 2//",
+	"-//ietf//dtd html 2.0 strict//",
+	"-//ietf//dtd html 2.0//",
+	"-//ietf//dtd html 2.1e//",
+	"-//ietf//dtd html 3.0//",
+	"-//ietf//dtd html 3.2 final//",
+	"-//ietf//dtd html 3.2//",
+	"-//ietf//dtd html 3//",
+	"-//ietf//dtd html level 0//",
+	"-//ietf//dtd html level 1//",
+	"-//ietf//dtd html level 2//",
+	"-//ietf//dtd html level 3//",
+	"-//ietf//dtd html strict level 0//",
+	"-//ietf//dtd html strict level 1//",
+	"-//ietf//dtd html strict level 2//",
+	"-//ietf//dtd html strict level 3//",
+	"-//ietf//dtd html strict//",
+	"-//ietf//dtd html//",
+	"-//metrius//dtd metrius presentational//",
+	"-//microsoft//dtd internet explorer 2.0 html strict//",
+	"-//microsoft//dtd internet explorer 2.0 html//",
+	"-//microsoft//dtd internet explorer 2.0 tables//",
+	"-//microsoft//dtd internet explorer 3.0 html strict//",
+	"-//microsoft//dtd internet explorer 3.0 html//",
+	"-//microsoft//dtd internet explorer 3.0 tables//",
+	"-//netscape comm. corp.//dtd html//",
+	"-//netscape comm. corp.//dtd strict html//",
+	"-//o'reilly and associates//dtd html 2.0//",
+	"-//o'reilly and associates//dtd html extended 1.0//",
+	"-//o'reilly and associates//dtd html extended relaxed 1.0//",
+	"-//sq//dtd html 2.0 hotmetal + extensions//",
+	"-//softquad software//dtd hotmetal pro 6.0::19990601::extensions to html 4.0//",
+	"-//softquad//dtd hotmetal pro 4.0::19971010::extensions to html 4.0//",
+	"-//spyglass//dtd html 2.0 extended//",
+	"-//sun microsystems corp.//dtd hotjava html//",
+	"-//sun microsystems corp.//dtd hotjava strict html//",
+	"-//w3c//dtd html 3 1995-03-24//",
+	"-//w3c//dtd html 3.2 draft//",
+	"-//w3c//dtd html 3.2 final//",
+	"-//w3c//dtd html 3.2//",
+	"-//w3c//dtd html 3.2s draft//",
+	"-//w3c//dtd html 4.0 frameset//",
+	"-//w3c//dtd html 4.0 transitional//",
+	"-//w3c//dtd html experimental 19960712//",
+	"-//w3c//dtd html experimental 970421//",
+	"-//w3c//dtd w3 html//",
+	"-//w3o//dtd w3 html 3.0//",
+	"-//webtechs//dtd mozilla html 2.0//",
+	"-//webtechs//dtd mozilla html//",
+}
+
+// doctypeMode returns "quirks", "limited-quirks" or "no-quirks" for the doctype.
+func doctypeMode(d *html.Node) string {
+	if d.Data != "html" {
+		return "quirks"
+	}
+	var public, system string
+	hasSystem := false
+	for _, a := range d.Attr {
+		switch a.Key {
+		case "public":
+			public = lower(a.Val)
+		case "system":
+			system = lower(a.Val)
+			hasSystem = true
+		}
+	}
+	switch {
+	case public == "-//w3o//dtd w3 html strict 3.0//en//", public == "-/w3c/dtd html 4.0 transitional/en", public == "html":
+		return "quirks"
+	case system == "http://www.ibm.com/data/dtd/v11/ibmxhtml1-transitional.dtd":
+		return "quirks"
+	case slices.ContainsFunc(quirksPublicIDPrefixes, func(p string) bool { return strings.HasPrefix(public, p) }):
+		return "quirks"
+	}
+	html401 := strings.HasPrefix(public, "-//w3c//dtd html 4.01 frameset//") ||
+		strings.HasPrefix(public, "-//w3c//dtd html 4.01 transitional//")
+	switch {
+	case html401 && !hasSystem:
+		return "quirks"
+	case html401,
+		strings.HasPrefix(public, "-//w3c//dtd xhtml 1.0 frameset//"),
+		strings.HasPrefix(public, "-//w3c//dtd xhtml 1.0 transitional//"):
+		return "limited-quirks"
+	}
+	return "no-quirks"
+}
+
+// diagnoseDoctype reports a missing or quirky doctype.
+func diagnoseDoctype(doc *html.Node, tokens []sourceToken, filename string) []*Issue {
+	for _, t := range tokens {
+		switch {
+		case t.typ == html.CommentToken:
+			continue
+		case t.typ == html.TextToken && strings.TrimFunc(t.data, isASCIIWhitespace) == "":
+			continue
+		case t.typ == html.DoctypeToken:
+			var d *html.Node
+			for c := doc.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.DoctypeNode {
+					d = c
+				}
+			}
+			if d == nil {
+				return nil
+			}
+			switch mode := doctypeMode(d); mode {
+			case "quirks", "limited-quirks":
+				return []*Issue{{Pos: t.pos, Message: "doctype renders the document in " + mode + " mode, use <!DOCTYPE html>"}}
+			}
+			return nil
+		}
+		return []*Issue{{Pos: t.pos, Message: "missing <!DOCTYPE html>, the document renders in quirks mode"}}
+	}
+	return []*Issue{{Pos: SourcePos{File: filename}, Message: "missing <!DOCTYPE html>, the document renders in quirks mode"}}
+}
+
+// impliedElements are the elements which the parser inserts without a start tag in valid documents.
+var impliedElements = map[string]bool{
+	"html": true, "head": true, "body": true, "tbody": true, "colgroup": true,
+}
+
+// diagnosis collects the issues of a tree aligned with the tokens.
+type diagnosis struct {
+	*sourceTree
+	issues []*Issue
+	// done are the elements whose end is already reported or explicitly closed.
+	done map[*html.Node]bool
+	// misnested are the start tags reported as misnested.
+	misnested map[int]bool
+}
+
+func (d *diagnosis) report(pos SourcePos, format string, a ...any) {
+	issue := NewIssue(nil, format, a...)
+	issue.Pos = pos
+	d.issues = append(d.issues, issue)
+}
+
+// startTag returns the start tag token of the element, if the parser created the element for it.
+func (d *diagnosis) startTag(n *html.Node) (sourceToken, bool) {
+	i, ok := d.origin[n]
+	if !ok || d.created[i] != n {
+		return sourceToken{}, false
+	}
+	return d.tokens[i], true
+}
+
+// needsEndTag reports whether the element must be closed by its end tag.
+// Foreign elements always need it or the self-closing syntax,
+// and the rules of void, optional and raw text end tags only apply to HTML elements.
+func needsEndTag(n *html.Node) bool {
+	if n.Namespace != "" {
+		return true
+	}
+	return !voidTags[n.Data] && !optionalEndTags[n.Data] && !rawTextTags[n.Data]
+}
+
+// isAncestorOrSelf reports whether a is n or an ancestor of n.
+func isAncestorOrSelf(a, n *html.Node) bool {
+	for ; n != nil; n = n.Parent {
+		if n == a {
+			return true
+		}
+	}
+	return false
+}
+
+// diagnoseElements reports the elements that the parser inserted, re-created or moved.
+func (d *diagnosis) diagnoseElements(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		i, ok := d.origin[c]
+		switch {
+		case !ok && !impliedElements[c.Data]:
+			d.report(d.insertedPos(c), "<%s> is inserted by the parser", c.Data)
+		case ok && d.created[i] != c:
+			if !d.misnested[i] {
+				d.misnested[i] = true
+				d.report(d.tokens[i].pos, "<%s> is misnested and re-created by the parser in <%s>", c.Data, c.Parent.Data)
+			}
+		case ok:
+			// a foster-parented element is inserted before the table which has an earlier start tag
+			for s := c.NextSibling; s != nil; s = s.NextSibling {
+				if j, ok := d.origin[s]; ok && d.created[j] == s && j < i {
+					d.report(d.tokens[i].pos, "<%s> is moved out of <%s> (foster parenting)", c.Data, s.Data)
+					break
+				}
+			}
+		}
+		d.diagnoseElements(c)
+	}
+}
+
+// insertedPos returns the position of the token for which the parser inserted the element:
+// the end tag such as </p>, the first start tag in it such as <td> of an inserted <tr>,
+// or the start tag of the nearest ancestor.
+func (d *diagnosis) insertedPos(n *html.Node) SourcePos {
+	if i, ok := d.endTag[n]; ok {
+		return d.tokens[i].pos
+	}
+	var first *sourceToken
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil && first == nil; c = c.NextSibling {
+			if t, ok := d.startTag(c); ok {
+				first = &t
+				return
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	if first != nil {
+		return first.pos
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if t, ok := d.startTag(p); ok {
+			return t.pos
+		}
+	}
+	return SourcePos{File: d.tokens[0].pos.File}
+}
+
+// diagnoseEndTag reports the elements the end tag closes implicitly, or the end tag if the parser ignores it.
+// The elements closed are the current node of the parser before the end tag and its ancestors
+// up to the current node after the end tag. A foster-parented element is not in the table,
+// so the ancestors end at the one which contains the current node after the end tag.
+func (d *diagnosis) diagnoseEndTag(i int) {
+	t := d.tokens[i]
+	before, after := d.before[i], d.after[i]
+	if before == nil || after == nil {
+		return
+	}
+	var closed []*html.Node
+	for n := before; n != nil && !isAncestorOrSelf(n, after); n = n.Parent {
+		closed = append(closed, n)
+	}
+	if len(closed) == 0 {
+		switch {
+		case t.name == "html", t.name == "head", t.name == "body":
+		case t.name == "p", t.name == "br":
+			// reported as the element inserted by the parser
+		case formattingTags[t.name] && d.isMisnested(t.name):
+			// reported as the misnested element
+		default:
+			d.report(t.pos, "stray end tag </%s> is ignored", t.name)
+		}
+		return
+	}
+
+	last := closed[len(closed)-1]
+	d.done[last] = true
+	if lower(last.Data) != t.name {
+		if s, ok := d.startTag(last); ok {
+			d.report(s.pos, "<%s> is closed by </%s>", last.Data, t.name)
+		}
+	}
+	for _, n := range closed[:len(closed)-1] {
+		s, ok := d.startTag(n)
+		if !ok || d.done[n] || !needsEndTag(n) {
+			continue
+		}
+		d.done[n] = true
+		if n.Namespace == "" && formattingTags[n.Data] {
+			d.misnested[d.origin[n]] = true
+			d.report(s.pos, "<%s> is misnested with </%s>", n.Data, t.name)
+			continue
+		}
+		d.report(s.pos, "<%s> is not closed before </%s>", n.Data, t.name)
+	}
+}
+
+// isMisnested reports whether a start tag with the name is reported as misnested
+// or the parser re-created an element from it.
+func (d *diagnosis) isMisnested(name string) bool {
+	for i := range d.misnested {
+		if d.tokens[i].name == name {
+			return true
+		}
+	}
+	for n, i := range d.origin {
+		if d.created[i] != n && d.tokens[i].name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// diagnoseTree reports the errors that the parser recovered from,
+// reading what the parser did for each token from the tree aligned with the tokens.
+func diagnoseTree(t *sourceTree, filename string) []*Issue {
+	if !t.aligned {
+		return []*Issue{{Pos: SourcePos{File: filename}, Message: "the parser errors are not reported because the tokens can not be aligned with the tree"}}
+	}
+	d := &diagnosis{sourceTree: t, done: map[*html.Node]bool{}, misnested: map[int]bool{}}
+	for i, tok := range t.tokens {
+		switch tok.typ {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			n, ok := t.created[i]
+			switch {
+			case !ok:
+				d.report(tok.pos, "start tag <%s> is ignored by the parser", tok.name)
+			case tok.typ == html.SelfClosingTagToken && n.Namespace == "" && !voidTags[n.Data]:
+				// the element left open is the same mistake
+				d.done[n] = true
+				d.report(tok.pos, "self-closing syntax is ignored on non-void element <%s/>", tok.name)
+			case tok.typ == html.SelfClosingTagToken:
+				d.done[n] = true
+			}
+		case html.TextToken:
+			if n := t.before[i]; n != nil && n.Namespace == "" && tableContexts[n.Data] {
+				d.report(tok.pos, "text is moved out of <table> (foster parenting)")
+			}
+		case html.EndTagToken:
+			d.diagnoseEndTag(i)
+		}
+	}
+	d.diagnoseElements(t.root)
+	for i, tok := range t.tokens {
+		n, ok := t.created[i]
+		if ok && !d.done[n] && !d.misnested[i] && needsEndTag(n) {
+			d.report(tok.pos, "<%s> is not closed", n.Data)
+		}
+	}
+	return d.issues
+}
+
+// ParseHTMLDiagnose is like ParseHTMLWithSourcePos but also reports
+// the errors that the parser silently recovers from, such as unclosed elements,
+// misnested formatting elements, elements inserted by the parser, stray end tags
+// and foster-parented table contents, and a doctype which makes the document render in quirks mode.
+// The errors are read from what the parser did for each token, so only the doctype
+// and an issue without a position saying so are reported if the tokens cannot be aligned with the tree.
+// The issues are sorted by their positions.
+func ParseHTMLDiagnose(r io.Reader, filename string) (*Document, []*Issue, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	t, err := parseSource(src, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	storeSourcePos(t, filename)
+
+	issues := slices.Concat(
+		diagnoseDoctype(t.root, t.tokens, filename),
+		diagnoseTree(t, filename),
+	)
+	slices.SortStableFunc(issues, func(a, b *Issue) int {
+		if a.Pos.Line != b.Pos.Line {
+			return a.Pos.Line - b.Pos.Line
+		}
+		return a.Pos.Col - b.Pos.Col
+	})
+	return (*Document)(t.root), issues, nil
+}
@@ -0,0 +1,142 @@
+package haat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseHTMLDiagnose(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			"valid",
+			"<!DOCTYPE html>\n<html><head><title>x</title></head><body><p>a<p>b<ul><li>1<li>2</ul><br><img src=x></body></html>",
+			nil,
+		},
+		{
+			"missing doctype",
+			"<html><body>x</body></html>",
+			[]string{"t.html:1:1: missing <!DOCTYPE html>, the document renders in quirks mode"},
+		},
+		{
+			"quirky doctype",
+			`<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN"><p>x`,
+			[]string{"t.html:1:1: doctype renders the document in quirks mode, use <!DOCTYPE html>"},
+		},
+		{
+			"limited quirks doctype",
+			`<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd"><p>x`,
+			[]string{"t.html:1:1: doctype renders the document in limited-quirks mode, use <!DOCTYPE html>"},
+		},
+		{
+			"unclosed",
+			"<!DOCTYPE html>\n<div><section><span>x</section>\n</div>",
+			[]string{"t.html:2:15: <span> is not closed before </section>"},
+		},
+		{
+			"unclosed at end",
+			"<!DOCTYPE html>\n<div>x",
+			[]string{"t.html:2:1: <div> is not closed"},
+		},
+		{
+			"misnested",
+			"<!DOCTYPE html>\n<p><b>1<i>2</b>3</i></p>",
+			[]string{"t.html:2:8: <i> is misnested with </b>"},
+		},
+		{
+			"stray end tag",
+			"<!DOCTYPE html>\n<div>x</span></div>",
+			[]string{"t.html:2:7: stray end tag </span> is ignored"},
+		},
+		{
+			"foster parenting",
+			"<!DOCTYPE html>\n<table><tr><td>1</td></tr>oops<div>x</div></table>",
+			[]string{
+				"t.html:2:27: text is moved out of <table> (foster parenting)",
+				"t.html:2:31: <div> is moved out of <table> (foster parenting)",
+			},
+		},
+		{
+			"self closing",
+			"<!DOCTYPE html>\n<div/>x<svg><path/></svg>",
+			[]string{
+				"t.html:2:1: self-closing syntax is ignored on non-void element <div/>",
+			},
+		},
+		{
+			"ignored start tag",
+			"<!DOCTYPE html>\n<body><p>x</p><body class=a></body>",
+			[]string{"t.html:2:15: start tag <body> is ignored by the parser"},
+		},
+		{
+			"misnested across implied end",
+			"<!DOCTYPE html><p><b>1<p>2</b>3</p><div><b>later</b></div>",
+			[]string{"t.html:1:19: <b> is misnested and re-created by the parser in <p>"},
+		},
+		{
+			"adoption agency",
+			"<!DOCTYPE html>\n<b>1<div>2</b>3</div>",
+			[]string{"t.html:2:1: <b> is misnested and re-created by the parser in <div>"},
+		},
+		{
+			"implied element",
+			"<!DOCTYPE html>\n<table><td>x</td></table>",
+			[]string{"t.html:2:8: <tr> is inserted by the parser"},
+		},
+		{
+			"implied by end tag",
+			"<!DOCTYPE html>\n<div>x</p></div><p>y</br></p>",
+			[]string{
+				"t.html:2:7: <p> is inserted by the parser",
+				"t.html:2:21: <br> is inserted by the parser",
+			},
+		},
+		{
+			"heading closed by other heading",
+			"<!DOCTYPE html>\n<h1>x</h2>",
+			[]string{"t.html:2:1: <h1> is closed by </h2>"},
+		},
+		{
+			"svg title and style",
+			"<!DOCTYPE html>\n<svg><title>Chart</title><style>rect { fill: red }</style><rect/></svg>" +
+				"<math><mi><style>a</style></mi></math><svg><foreignObject><title>x</title></foreignObject></svg>",
+			nil,
+		},
+		{
+			"svg title with child",
+			"<!DOCTYPE html>\n<svg><title>a<tspan>b</title></svg>",
+			[]string{"t.html:2:14: <tspan> is not closed before </title>"},
+		},
+		{
+			"unaligned",
+			"<!DOCTYPE html>\n<pre></b>\nx</pre>",
+			[]string{"the parser errors are not reported because the tokens can not be aligned with the tree"},
+		},
+		{
+			"unclosed foreign element",
+			"<!DOCTYPE html>\n<svg><g><path/></svg>",
+			[]string{"t.html:2:6: <g> is not closed before </svg>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, issues, err := ParseHTMLDiagnose(strings.NewReader(tt.src), "t.html")
+			if err != nil {
+				t.Fatalf("got: %v\nwant: %v", err, nil)
+			}
+			defer doc.ForgetSourcePos()
+
+			var actual []string
+			for _, i := range issues {
+				actual = append(actual, i.Error())
+			}
+			if strings.Join(actual, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got: %q\nwant: %q", actual, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package haat
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// ChangeKind is the kind of a change found by Diff.
+type ChangeKind int
+
+const (
+	// NodeAdded is a node only in the second tree.
+	NodeAdded ChangeKind = iota
+	// NodeRemoved is a node only in the first tree.
+	NodeRemoved
+	// NodeChanged is a text, comment or doctype node whose data is changed.
+	NodeChanged
+	// AttrAdded is an attribute only in the second tree.
+	AttrAdded
+	// AttrRemoved is an attribute only in the first tree.
+	AttrRemoved
+	// AttrChanged is an attribute whose value is changed.
+	AttrChanged
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case NodeAdded:
+		return "NodeAdded"
+	case NodeRemoved:
+		return "NodeRemoved"
+	case NodeChanged:
+		return "NodeChanged"
+	case AttrAdded:
+		return "AttrAdded"
+	case AttrRemoved:
+		return "AttrRemoved"
+	case AttrChanged:
+		return "AttrChanged"
+	}
+	return "ChangeKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Change is a difference between two trees.
+// Path is the XPath-like path of the node, such as "/html/body/p[2]/text()[1]".
+// Old and New are the values of the attribute or the data of the node,
+// or the HTML of an added or removed node.
+type Change struct {
+	Kind ChangeKind
+	Path string
+	Attr string
+	Old  string
+	New  string
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case NodeAdded:
+		return fmt.Sprintf("+ %s: %s", c.Path, quoteIfNeeded(c.New))
+	case NodeRemoved:
+		return fmt.Sprintf("- %s: %s", c.Path, quoteIfNeeded(c.Old))
+	case NodeChanged:
+		return fmt.Sprintf("~ %s: %q -> %q", c.Path, c.Old, c.New)
+	case AttrAdded:
+		return fmt.Sprintf("+ %s@%s: %q", c.Path, c.Attr, c.New)
+	case AttrRemoved:
+		return fmt.Sprintf("- %s@%s: %q", c.Path, c.Attr, c.Old)
+	case AttrChanged:
+		return fmt.Sprintf("~ %s@%s: %q -> %q", c.Path, c.Attr, c.Old, c.New)
+	}
+	return c.Kind.String() + " " + c.Path
+}
+
+// quoteIfNeeded quotes the string if it has control characters or surrounding spaces.
+func quoteIfNeeded(s string) string {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsFunc(s, unicode.IsControl) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// FormatChanges formats the changes one per line for test failure messages.
+func FormatChanges(changes []Change) string {
+	lines := make([]string, len(changes))
+	for i, c := range changes {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DiffOptions specifies how Diff compares the trees.
+type DiffOptions struct {
+	// IgnoreWhitespace skips whitespace-only text nodes and
+	// compares text nodes with whitespace collapsed.
+	IgnoreWhitespace bool
+	// IgnoreComments skips comment nodes.
+	IgnoreComments bool
+}
+
+// Diff compares the trees structurally and returns the changes from a to b.
+// Attribute order is ignored. Children are aligned by the longest common subsequence
+// of their node types and tag names. A nil opts is the same as the zero value.
+func Diff(a, b Node, opts *DiffOptions) []Change {
+	if opts == nil {
+		opts = &DiffOptions{}
+	}
+	d := &differ{opts: opts}
+	na, nb := convertNode(a), convertNode(b)
+	path := ""
+	if na.Type == html.ElementNode {
+		path = "/" + na.Data
+	}
+	if !sameKind(na, nb) {
+		d.add(Change{Kind: NodeRemoved, Path: path, Old: outerHTML(na)})
+		d.add(Change{Kind: NodeAdded, Path: path, New: outerHTML(nb)})
+		return d.changes
+	}
+	d.node(na, nb, path)
+	return d.changes
+}
+
+type differ struct {
+	opts    *DiffOptions
+	changes []Change
+}
+
+func (d *differ) add(c Change) {
+	d.changes = append(d.changes, c)
+}
+
+// skip reports whether the node is ignored by the options.
+func (d *differ) skip(n *html.Node) bool {
+	switch n.Type {
+	case html.TextNode:
+		return d.opts.IgnoreWhitespace && strings.TrimFunc(n.Data, isASCIIWhitespace) == ""
+	case html.CommentNode:
+		return d.opts.IgnoreComments
+	}
+	return false
+}
+
+// data returns the data of the node to compare.
+func (d *differ) data(n *html.Node) string {
+	if n.Type == html.TextNode && d.opts.IgnoreWhitespace {
+		return strings.Join(strings.FieldsFunc(n.Data, isASCIIWhitespace), " ")
+	}
+	return n.Data
+}
+
+// sameKind reports whether the nodes are aligned as the same node.
+func sameKind(a, b *html.Node) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	if a.Type == html.ElementNode {
+		return a.Data == b.Data && a.Namespace == b.Namespace
+	}
+	return true
+}
+
+// stepName returns the path step of the node without index.
+func stepName(n *html.Node) string {
+	switch n.Type {
+	case html.ElementNode:
+		return n.Data
+	case html.TextNode:
+		return "text()"
+	case html.CommentNode:
+		return "comment()"
+	case html.DoctypeNode:
+		return "doctype()"
+	case html.RawNode:
+		return "raw()"
+	}
+	return "node()"
+}
+
+// steps returns the path steps of the nodes.
+// A step has an index only if there are siblings with the same name.
+func steps(nodes []*html.Node) []string {
+	total := map[string]int{}
+	for _, n := range nodes {
+		total[stepName(n)]++
+	}
+	count := map[string]int{}
+	ss := make([]string, len(nodes))
+	for i, n := range nodes {
+		name := stepName(n)
+		count[name]++
+		ss[i] = name
+		if total[name] > 1 {
+			ss[i] += "[" + strconv.Itoa(count[name]) + "]"
+		}
+	}
+	return ss
+}
+
+func outerHTML(n *html.Node) string {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, n); err != nil {
+		return "<" + typeString(n.Type) + ">"
+	}
+	return buf.String()
+}
+
+func (d *differ) node(a, b *html.Node, path string) {
+	switch a.Type {
+	case html.TextNode, html.CommentNode, html.RawNode:
+		if d.data(a) != d.data(b) {
+			d.add(Change{Kind: NodeChanged, Path: path, Old: a.Data, New: b.Data})
+		}
+		return
+	case html.DoctypeNode:
+		if outerHTML(a) != outerHTML(b) {
+			d.add(Change{Kind: NodeChanged, Path: path, Old: outerHTML(a), New: outerHTML(b)})
+		}
+		return
+	case html.ElementNode:
+		d.attrs(a, b, path)
+	}
+	d.children(a, b, path)
+}
+
+func (d *differ) attrs(a, b *html.Node, path string) {
+	old := map[string]string{}
+	for _, at := range a.Attr {
+		old[qualifiedName(at)] = at.Val
+	}
+	seen := map[string]bool{}
+	for _, at := range b.Attr {
+		name := qualifiedName(at)
+		seen[name] = true
+		if v, ok := old[name]; !ok {
+			d.add(Change{Kind: AttrAdded, Path: path, Attr: name, New: at.Val})
+		} else if v != at.Val {
+			d.add(Change{Kind: AttrChanged, Path: path, Attr: name, Old: v, New: at.Val})
+		}
+	}
+	for _, at := range a.Attr {
+		if name := qualifiedName(at); !seen[name] {
+			seen[name] = true
+			d.add(Change{Kind: AttrRemoved, Path: path, Attr: name, Old: at.Val})
+		}
+	}
+}
+
+func (d *differ) children(a, b *html.Node, path string) {
+	var as, bs []*html.Node
+	for c := a.FirstChild; c != nil; c = c.NextSibling {
+		if !d.skip(c) {
+			as = append(as, c)
+		}
+	}
+	for c := b.FirstChild; c != nil; c = c.NextSibling {
+		if !d.skip(c) {
+			bs = append(bs, c)
+		}
+	}
+	aSteps, bSteps := steps(as), steps(bs)
+
+	// lcs[i][j] is the length of the longest common subsequence of as[i:] and bs[j:].
+	lcs := make([][]int, len(as)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bs)+1)
+	}
+	for i := len(as) - 1; i >= 0; i-- {
+		for j := len(bs) - 1; j >= 0; j-- {
+			if sameKind(as[i], bs[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(as) || j < len(bs) {
+		switch {
+		case i < len(as) && j < len(bs) && sameKind(as[i], bs[j]) && lcs[i][j] == lcs[i+1][j+1]+1:
+			d.node(as[i], bs[j], path+"/"+aSteps[i])
+			i++
+			j++
+		case i < len(as) && (j == len(bs) || lcs[i+1][j] >= lcs[i][j+1]):
+			d.add(Change{Kind: NodeRemoved, Path: path + "/" + aSteps[i], Old: outerHTML(as[i])})
+			i++
+		default:
+			d.add(Change{Kind: NodeAdded, Path: path + "/" + bSteps[j], New: outerHTML(bs[j])})
+			j++
+		}
+	}
+}
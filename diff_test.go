@@ -0,0 +1,46 @@
+package haat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	parse := func(s string) *Document {
+		ht, err := ParseHTML(strings.NewReader(s))
+		if err != nil {
+			t.Fatalf("got: %v\nwant: %v", err, nil)
+		}
+		return ht
+	}
+
+	a := parse(`<!DOCTYPE html><html><head></head><body>
+<h1 class="x" id="t">Title</h1>
+<p>one</p><!-- c -->
+<p>two</p>
+</body></html>`)
+	b := parse(`<!DOCTYPE html><html><head></head><body><h1 id="t" title="new">Title</h1>
+<ul><li>1</li></ul>
+<p>two  </p></body></html>`)
+
+	if changes := Diff(a, a.Clone(), nil); len(changes) != 0 {
+		t.Errorf("got: %v\nwant: %v", FormatChanges(changes), "")
+	}
+
+	expected := `+ /html/body/h1@title: "new"
+- /html/body/h1@class: "x"
+- /html/body/p[1]: <p>one</p>
++ /html/body/ul: <ul><li>1</li></ul>`
+	actual := FormatChanges(Diff(a, b, &DiffOptions{IgnoreWhitespace: true, IgnoreComments: true}))
+	if actual != expected {
+		t.Errorf("got:\n%v\nwant:\n%v", actual, expected)
+	}
+
+	actual = FormatChanges(Diff(a, b, nil))
+	if !strings.HasPrefix(actual, `- /html/body/text()[1]: "\n"`) || !strings.Contains(actual, `~ /html/body/p[2]/text(): "two" -> "two  "`) {
+		t.Errorf("got:\n%v\nwant: whitespace changes", actual)
+	}
+	if !strings.Contains(actual, `- /html/body/comment(): <!-- c -->`) {
+		t.Errorf("got:\n%v\nwant: comment changes", actual)
+	}
+}
@@ -0,0 +1,238 @@
+package haat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultDumpMaxText is the number of characters of text shown by Dump when DumpOptions.MaxText is 0.
+const DefaultDumpMaxText = 60
+
+// DumpOptions specifies the options of Dump.
+type DumpOptions struct {
+	// MaxDepth is the depth of the deepest nodes shown. The children below it are counted.
+	// 0 means no limit.
+	MaxDepth int
+	// MaxText is the number of characters of text, comment and raw text shown.
+	// 0 means DefaultDumpMaxText and a negative value means no limit.
+	MaxText int
+	// JSON writes the tree as indented JSON instead of text.
+	JSON bool
+}
+
+// jsonAttr is an attribute in the JSON form of a node.
+type jsonAttr struct {
+	Namespace string `json:"namespace,omitempty"`
+	Key       string `json:"key"`
+	Val       string `json:"val"`
+}
+
+// jsonNode is the JSON form of a node.
+type jsonNode struct {
+	Type      string      `json:"type"`
+	Namespace string      `json:"namespace,omitempty"`
+	Data      string      `json:"data,omitempty"`
+	Attr      []jsonAttr  `json:"attr,omitempty"`
+	Children  []*jsonNode `json:"children,omitempty"`
+	// More is the number of the children omitted by DumpOptions.MaxDepth.
+	More int `json:"more,omitempty"`
+	// Truncated reports whether Data is truncated by DumpOptions.MaxText.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// jsonNodeTypes maps the node types to the type names in the JSON form.
+var jsonNodeTypes = map[html.NodeType]string{
+	html.ErrorNode:    "error",
+	html.TextNode:     "text",
+	html.DocumentNode: "document",
+	html.ElementNode:  "element",
+	html.CommentNode:  "comment",
+	html.DoctypeNode:  "doctype",
+	html.RawNode:      "raw",
+}
+
+// truncate returns the first max characters of the string and whether it is truncated.
+func truncate(s string, max int) (string, bool) {
+	if max < 0 {
+		return s, false
+	}
+	i := 0
+	for j := range s {
+		if i == max {
+			return s[:j], true
+		}
+		i++
+	}
+	return s, false
+}
+
+// newJSONNode returns the JSON form of the node and its descendants down to maxDepth.
+func newJSONNode(n *html.Node, depth, maxDepth, maxText int) *jsonNode {
+	j := &jsonNode{
+		Type:      jsonNodeTypes[n.Type],
+		Namespace: n.Namespace,
+		Data:      n.Data,
+	}
+	if n.Type != html.ElementNode && n.Type != html.DoctypeNode {
+		j.Data, j.Truncated = truncate(n.Data, maxText)
+	}
+	for _, a := range n.Attr {
+		j.Attr = append(j.Attr, jsonAttr{Namespace: a.Namespace, Key: a.Key, Val: a.Val})
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if maxDepth > 0 && depth >= maxDepth {
+			j.More++
+			continue
+		}
+		j.Children = append(j.Children, newJSONNode(c, depth+1, maxDepth, maxText))
+	}
+	return j
+}
+
+// quoteText quotes the text with visible whitespace, truncated to max characters.
+func quoteText(s string, max int) string {
+	s, truncated := truncate(s, max)
+	q := strconv.Quote(s)
+	if truncated {
+		q += "…"
+	}
+	return q
+}
+
+// elementName returns the tag name of the element with its namespace prefix.
+func elementName(n *html.Node) string {
+	if n.Namespace == "" {
+		return n.Data
+	}
+	return n.Namespace + ":" + n.Data
+}
+
+// nodeLine returns the description of the node without its children.
+func nodeLine(n *html.Node, maxText int) string {
+	var b strings.Builder
+	b.WriteString(typeString(n.Type))
+	switch n.Type {
+	case html.ElementNode, html.DoctypeNode:
+		b.WriteString(" " + elementName(n))
+		for _, a := range n.Attr {
+			fmt.Fprintf(&b, " %s=%s", qualifiedName(a), quoteText(a.Val, maxText))
+		}
+	case html.TextNode, html.CommentNode, html.RawNode:
+		b.WriteString(" " + quoteText(n.Data, maxText))
+	}
+	return b.String()
+}
+
+func dumpTree(w io.Writer, n *html.Node, depth int, opts DumpOptions) error {
+	if _, err := fmt.Fprintf(w, "%*s%s\n", depth*2, "", nodeLine(n, opts.MaxText)); err != nil {
+		return err
+	}
+	if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+		more := 0
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			more++
+		}
+		if more > 0 {
+			_, err := fmt.Fprintf(w, "%*s… %d more\n", depth*2+2, "", more)
+			return err
+		}
+		return nil
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if err := dumpTree(w, c, depth+1, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dump writes the node and its descendants as an indented tree
+// with the node types, namespaces and attributes, one node per line.
+// Text is quoted to make whitespace visible and truncated to DumpOptions.MaxText characters.
+// With DumpOptions.JSON, the tree is written as indented JSON.
+func Dump(w io.Writer, n Node, opts DumpOptions) error {
+	if opts.MaxText == 0 {
+		opts.MaxText = DefaultDumpMaxText
+	}
+	node := convertNode(n)
+	if opts.JSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(newJSONNode(node, 0, opts.MaxDepth, opts.MaxText))
+	}
+	return dumpTree(w, node, 0, opts)
+}
+
+// shortString returns the short description of the node such as its start tag.
+func shortString(n *html.Node) string {
+	switch n.Type {
+	case html.DocumentNode:
+		return "#document"
+	case html.ElementNode:
+		var b strings.Builder
+		b.WriteString("<" + elementName(n))
+		for _, a := range n.Attr {
+			fmt.Fprintf(&b, " %s=%s", qualifiedName(a), quoteText(a.Val, DefaultDumpMaxText))
+		}
+		b.WriteString(">")
+		return b.String()
+	case html.DoctypeNode:
+		return "<!DOCTYPE " + n.Data + ">"
+	case html.CommentNode:
+		return "<!--" + quoteText(n.Data, DefaultDumpMaxText) + "-->"
+	}
+	return quoteText(n.Data, DefaultDumpMaxText)
+}
+
+// formatNode implements fmt.Formatter for the node types.
+// The %v and %s verbs format the short description of the node,
+// and %+v formats the tree of Dump.
+func formatNode(f fmt.State, verb rune, n *html.Node) {
+	switch {
+	case n == nil:
+		io.WriteString(f, "<nil>")
+	case verb == 'v' && f.Flag('+'):
+		var b strings.Builder
+		dumpTree(&b, n, 0, DumpOptions{MaxText: DefaultDumpMaxText})
+		io.WriteString(f, strings.TrimSuffix(b.String(), "\n"))
+	case verb == 'v' || verb == 's':
+		io.WriteString(f, shortString(n))
+	default:
+		fmt.Fprintf(f, "%%!%c(%s)", verb, shortString(n))
+	}
+}
+
+// Format implements fmt.Formatter. %v prints the short description and %+v prints the tree.
+func (d *Document) Format(f fmt.State, verb rune) {
+	formatNode(f, verb, (*html.Node)(d))
+}
+
+// Format implements fmt.Formatter. %v prints the start tag and %+v prints the tree.
+func (e *Element) Format(f fmt.State, verb rune) {
+	formatNode(f, verb, (*html.Node)(e))
+}
+
+// Format implements fmt.Formatter. %v prints the quoted text.
+func (t *Text) Format(f fmt.State, verb rune) {
+	formatNode(f, verb, (*html.Node)(t))
+}
+
+// Format implements fmt.Formatter. %v prints the quoted text.
+func (t *RawText) Format(f fmt.State, verb rune) {
+	formatNode(f, verb, (*html.Node)(t))
+}
+
+// Format implements fmt.Formatter. %v prints the doctype declaration.
+func (d *Doctype) Format(f fmt.State, verb rune) {
+	formatNode(f, verb, (*html.Node)(d))
+}
+
+// Format implements fmt.Formatter. %v prints the comment with the quoted data.
+func (c *Comment) Format(f fmt.State, verb rune) {
+	formatNode(f, verb, (*html.Node)(c))
+}
@@ -0,0 +1,118 @@
+package haat
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html/atom"
+)
+
+func dumpPage() *Document {
+	return NewDocument(
+		E(atom.Head).C(E(atom.Title).C(T("Hello"))),
+		E(atom.Body).C(
+			E(atom.P).SetA(AttrID("a"), A("class", "x")).C(T("line one\n\tline two")),
+			NewComment(" note "),
+			NewElementNS(NamespaceSVG, "svg").C(
+				NewElementNS(NamespaceSVG, "use").SetA(AttrXlinkHref("#icon")),
+			),
+		),
+	)
+}
+
+func TestDump(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Dump(&buf, dumpPage(), DumpOptions{MaxText: 12}); err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+	expected := `Document
+  Doctype html
+  Element html
+    Element head
+      Element title
+        Text "Hello"
+    Element body
+      Element p class="x" id="a"
+        Text "line one\n\tli"…
+      Comment " note "
+      Element svg:svg
+        Element svg:use xlink:href="#icon"
+`
+	if actual := buf.String(); actual != expected {
+		t.Errorf("got:\n%v\nwant:\n%v", actual, expected)
+	}
+
+	buf.Reset()
+	if err := Dump(&buf, dumpPage(), DumpOptions{MaxDepth: 2}); err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+	expected = `Document
+  Doctype html
+  Element html
+    Element head
+      … 1 more
+    Element body
+      … 3 more
+`
+	if actual := buf.String(); actual != expected {
+		t.Errorf("got:\n%v\nwant:\n%v", actual, expected)
+	}
+}
+
+func TestDumpJSON(t *testing.T) {
+	var buf bytes.Buffer
+	svg := dumpPage().Query("svg")[0]
+	if err := Dump(&buf, svg, DumpOptions{JSON: true}); err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+	expected := `{
+  "type": "element",
+  "namespace": "svg",
+  "data": "svg",
+  "children": [
+    {
+      "type": "element",
+      "namespace": "svg",
+      "data": "use",
+      "attr": [
+        {
+          "namespace": "xlink",
+          "key": "href",
+          "val": "#icon"
+        }
+      ]
+    }
+  ]
+}
+`
+	if actual := buf.String(); actual != expected {
+		t.Errorf("got:\n%v\nwant:\n%v", actual, expected)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	p := dumpPage().Query("p")[0]
+	testCases := []struct {
+		format string
+		arg    any
+		want   string
+	}{
+		{"%v", p, `<p class="x" id="a">`},
+		{"%s", p, `<p class="x" id="a">`},
+		{"%v", (*Text)(p.FirstChild), `"line one\n\tline two"`},
+		{"%v", (*Comment)(p.NextSibling), `<!--" note "-->`},
+		{"%+v", p, "Element p class=\"x\" id=\"a\"\n  Text \"line one\\n\\tline two\""},
+		{"%d", p, `%!d(<p class="x" id="a">)`},
+		{"%v", (*Element)(nil), `<nil>`},
+	}
+	for _, tc := range testCases {
+		if actual := fmt.Sprintf(tc.format, tc.arg); actual != tc.want {
+			t.Errorf("%s: got: %v\nwant: %v", tc.format, actual, tc.want)
+		}
+	}
+	if actual := fmt.Sprintf("%v", dumpPage()); !strings.HasPrefix(actual, "#document") {
+		t.Errorf("got: %v\nwant: #document", actual)
+	}
+}
@@ -0,0 +1,73 @@
+package haat
+
+import (
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+// SwapOOBAttr is the attribute of htmx which swaps the element out of band.
+const SwapOOBAttr = "hx-swap-oob"
+
+// RefAttr is the attribute which names an element of a template for the code generated by haatgen.
+const RefAttr = "data-haat-ref"
+
+// ElementByID returns the first element with the given id, or nil if there is none.
+func (d *Document) ElementByID(id string) *Element {
+	return elementByAttr((*html.Node)(d), "id", id)
+}
+
+func (e *Element) ElementByID(id string) *Element {
+	return elementByAttr((*html.Node)(e), "id", id)
+}
+
+// ElementByRef returns the first element with the given data-haat-ref attribute, or nil if there is none.
+func (d *Document) ElementByRef(ref string) *Element {
+	return elementByAttr((*html.Node)(d), RefAttr, ref)
+}
+
+func (e *Element) ElementByRef(ref string) *Element {
+	return elementByAttr((*html.Node)(e), RefAttr, ref)
+}
+
+func elementByAttr(n *html.Node, key, val string) *Element {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if e := (*Element)(c); e.hasAttr(key) && e.GetAttr(key) == val {
+			return e
+		}
+		if e := elementByAttr(c, key, val); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// RenderFragment renders the outer HTML of the first element that matches the selector.
+// It returns an error if no element matches.
+func (d *Document) RenderFragment(w io.Writer, selector string, checker ...Checker) error {
+	e, err := d.QueryOne(selector)
+	if err != nil {
+		return err
+	}
+	return e.Render(w, checker...)
+}
+
+// RenderOOB renders the elements that match the selectors with the hx-swap-oob attribute
+// for the out of band swap of htmx. The document itself is not modified.
+func (d *Document) RenderOOB(w io.Writer, selector ...string) error {
+	for _, s := range selector {
+		for _, e := range d.Query(s) {
+			oob := e.Clone()
+			if !oob.hasAttr(SwapOOBAttr) {
+				oob.SetA(NewAttribute(SwapOOBAttr, "true"))
+			}
+			if err := oob.Render(w); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
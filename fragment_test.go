@@ -0,0 +1,56 @@
+package haat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderFragment(t *testing.T) {
+	ht, err := ParseHTML(strings.NewReader(`<!DOCTYPE html><html><head></head><body>
+<nav><span id="count">3</span></nav><main><ul id="list"><li>a</li></ul></main>
+<div id="toast" hx-swap-oob="afterbegin">saved</div></body></html>`))
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+
+	var buf bytes.Buffer
+	if err := ht.RenderFragment(&buf, "#list"); err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+	if err := ht.RenderOOB(&buf, "#count", "#toast"); err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+
+	expected := `<ul id="list"><li>a</li></ul><span hx-swap-oob="true" id="count">3</span><div id="toast" hx-swap-oob="afterbegin">saved</div>`
+	actual := buf.String()
+	if actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+	if ht.ElementByID("count").hasAttr(SwapOOBAttr) {
+		t.Errorf("got: %v\nwant: %v", ht.ElementByID("count").Attr, "no hx-swap-oob")
+	}
+
+	if err := ht.RenderFragment(&buf, "#nothing"); err == nil {
+		t.Errorf("got: %v\nwant: error", err)
+	}
+}
+
+func TestElementByRef(t *testing.T) {
+	ht, err := ParseHTML(strings.NewReader(`<p id="a"><span data-haat-ref="name">x</span></p><p data-haat-ref="">y</p>`))
+	if err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+	if e := ht.ElementByRef("name"); e == nil || e.TextContent() != "x" {
+		t.Errorf("got: %v\nwant: %v", e, "span")
+	}
+	if e := ht.ElementByID("a").ElementByRef("name"); e == nil || e.TextContent() != "x" {
+		t.Errorf("got: %v\nwant: %v", e, "span")
+	}
+	if e := ht.ElementByRef(""); e == nil || e.TextContent() != "y" {
+		t.Errorf("got: %v\nwant: %v", e, "p")
+	}
+	if e := ht.ElementByID(""); e != nil {
+		t.Errorf("got: %v\nwant: %v", e, nil)
+	}
+}
@@ -1,6 +1,7 @@
 module github.com/turutcrane/haat
 
-go 1.23.0
+// Go 1.24 is required for weak pointers, which keep the source positions.
+go 1.24.0
 
 require (
 	github.com/ericchiang/css v1.4.0
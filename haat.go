@@ -144,10 +144,12 @@ func Remove(n Node) {
 }
 
 // RemoveAttr removes the attribute with the given key from the node.
+// A namespaced attribute is specified by its qualified name such as "xlink:href".
 func (e *Element) RemoveAttr(key string) *Element {
+	key = adjustAttrKey(e.Namespace, key)
 	attr := make([]html.Attribute, 0, len(e.Attr))
 	for _, a := range e.Attr {
-		if a.Key != lower(key) {
+		if qualifiedName(a) != key {
 			attr = append(attr, a)
 		}
 	}
@@ -157,15 +159,7 @@ func (e *Element) RemoveAttr(key string) *Element {
 
 // RemoveClass removes the class from the class attribute of the node.
 func (e *Element) RemoveClasses(delClass ...string) *Element {
-	classes := strings.Split(e.GetAttr("class"), " ")
-	cs := make([]string, 0, len(classes))
-	for _, c := range classes {
-		if !slices.Contains(delClass, c) {
-			cs = append(cs, c)
-		}
-	}
-	e.SetA(NewAttribute("class", strings.Join(cs, " ")))
-	return e
+	return e.defaultClassList().Remove(delClass...)
 }
 
 // Clone node
@@ -225,28 +219,106 @@ func A(key, value string) Attribute {
 }
 
 // NewAttribute creates a new attribute with the given key and value.
+// The key is lowercased and, when the attribute is set on an SVG or MathML element,
+// its case is adjusted as the HTML parser does, e.g. "viewBox".
+// The keys "xlink:*", "xml:*" and "xmlns:*" are split into namespace and local name
+// in the same way as the HTML parser does for foreign elements.
 func NewAttribute(key, value string) Attribute {
+	key = lower(key)
+	if i := strings.IndexByte(key, ':'); i > 0 {
+		switch key[:i] {
+		case "xlink", "xml", "xmlns":
+			return NewAttributeNS(key[:i], key[i+1:], value)
+		}
+	}
 	return (Attribute)(html.Attribute{
-		Key: lower(key),
+		Key: key,
 		Val: value,
 	})
 }
 
+// NewAttributeNS creates a new attribute with the given namespace prefix, key and value.
+func NewAttributeNS(namespace, key, value string) Attribute {
+	return (Attribute)(html.Attribute{
+		Namespace: lower(namespace),
+		Key:       lower(key),
+		Val:       value,
+	})
+}
+
+// qualifiedName returns the attribute name with its namespace prefix.
+func qualifiedName(a html.Attribute) string {
+	if a.Namespace == "" {
+		return a.Key
+	}
+	return a.Namespace + ":" + a.Key
+}
+
+// QualifiedName returns the attribute name with its namespace prefix such as "xlink:href".
+func (a Attribute) QualifiedName() string {
+	return qualifiedName(html.Attribute(a))
+}
+
+// AttrOrder specifies the order of attributes set by ReplaceAttrs and SetA.
+type AttrOrder int
+
+const (
+	// AttrOrderSorted sorts attributes alphabetically by key.
+	AttrOrderSorted AttrOrder = iota
+	// AttrOrderSource keeps attributes in the order of first appearance.
+	// Overwritten keys stay in place and new keys are appended.
+	AttrOrderSource
+)
+
 // ReplaceAttrs replaces all attributes with the attributes specified in the arguments.
 // If there are duplicate keys, it sets the latter value.
+// The attributes are sorted; ReplaceAttrsOrder, SetAOrder, SetBoolAOrder and ClassListOptions
+// take the order per call, so that no process-wide setting is shared by the callers.
 func (e *Element) ReplaceAttrs(attrs ...Attribute) *Element {
+	return e.ReplaceAttrsOrder(AttrOrderSorted, attrs...)
+}
+
+// ReplaceAttrsOrder is like ReplaceAttrs but orders the attributes by the given order.
+func (e *Element) ReplaceAttrsOrder(order AttrOrder, attrs ...Attribute) *Element {
+	if e.Namespace != NamespaceHTML {
+		attrs = slices.Clone(attrs)
+		for i, a := range attrs {
+			if a.Namespace == "" {
+				attrs[i].Key = adjustAttrKey(e.Namespace, a.Key)
+			}
+		}
+	}
+	if order == AttrOrderSource {
+		index := map[string]int{}
+		var newAttrs []html.Attribute
+		for _, a := range attrs {
+			if a.Key != "" {
+				key := qualifiedName(html.Attribute(a))
+				if i, ok := index[key]; ok {
+					newAttrs[i] = html.Attribute(a)
+					continue
+				}
+				index[key] = len(newAttrs)
+				newAttrs = append(newAttrs, html.Attribute(a))
+			}
+		}
+		e.Attr = newAttrs
+		return e
+	}
+
 	slices.SortStableFunc(attrs, func(a, b Attribute) int {
-		return strings.Compare(lower(a.Key), lower(b.Key))
+		return strings.Compare(lower(qualifiedName(html.Attribute(a))), lower(qualifiedName(html.Attribute(b))))
 	})
 
 	lastKey := ""
 	var newAttrs []html.Attribute
 	for _, a := range attrs {
 		if a.Key != "" {
-			if a.Key == lastKey {
+			key := qualifiedName(html.Attribute(a))
+			if key == lastKey {
 				newAttrs = slices.Delete(newAttrs, len(newAttrs)-1, len(newAttrs))
 			}
-			lastKey = a.Key
+			lastKey = key
 			newAttrs = append(newAttrs, html.Attribute(a))
 		}
 	}
@@ -258,17 +330,27 @@ func (e *Element) ReplaceAttrs(attrs ...Attribute) *Element {
 // SetA appends the given attributes to the attributes of the node.
 // If keys are already in the attributes of the node, the values are overwritten.
 func (e *Element) SetA(attr ...Attribute) *Element {
+	return e.SetAOrder(AttrOrderSorted, attr...)
+}
+
+// SetAOrder is like SetA but orders the attributes by the given order.
+func (e *Element) SetAOrder(order AttrOrder, attr ...Attribute) *Element {
 	var attrs []Attribute
 	for _, a := range e.Attr {
 		attrs = append(attrs, Attribute(a))
 	}
-	return e.ReplaceAttrs(slices.Concat(attrs, attr)...)
+	return e.ReplaceAttrsOrder(order, slices.Concat(attrs, attr)...)
 }
 
 // SetBoolA appends the given boolean attributes to the attributes of the node or removes them.
 func (e *Element) SetBoolA(key string, v bool) *Element {
+	return e.SetBoolAOrder(AttrOrderSorted, key, v)
+}
+
+// SetBoolAOrder is like SetBoolA but orders the attributes by the given order.
+func (e *Element) SetBoolAOrder(order AttrOrder, key string, v bool) *Element {
 	if v {
-		return e.SetA(NewAttribute(key, ""))
+		return e.SetAOrder(order, NewAttribute(key, ""))
 	}
 	return e.RemoveAttr(key)
 }
@@ -278,6 +360,12 @@ func AttrHref(u url.URL) Attribute {
 	return NewAttribute("href", u.String())
 }
 
+// AttrXlinkHref creates a new attribute "xlink:href" with the given value.
+// It is used to refer to other elements from SVG elements such as <use>.
+func AttrXlinkHref(href string) Attribute {
+	return NewAttributeNS("xlink", "href", href)
+}
+
 // AttrID creates a new attribute with the key "id" and the given value.
 func AttrID(id string) Attribute {
 	return NewAttribute("id", id)
@@ -285,14 +373,10 @@ func AttrID(id string) Attribute {
 
 // SetClasses sets the given class to the class attribute of the node.
 // Class duplication check is performed.
+// The classes are sorted when they are added to existing ones.
+// Use ClassList to keep insertion order.
 func (e *Element) SetClasses(classes ...string) *Element {
-	old := e.GetAttr("class")
-	if old == "" {
-		return e.SetA(NewAttribute("class", strings.Join(classes, " ")))
-	}
-	newClasses := slices.Concat(strings.Split(old, " "), classes)
-	slices.Sort(newClasses)
-	return e.SetA(NewAttribute("class", strings.Join(slices.Compact(newClasses), " ")))
+	return e.defaultClassList().Set(classes...)
 }
 
 // Lf creates a new text node with a line feed.
@@ -334,6 +418,94 @@ func E(a atom.Atom) *Element {
 	return NewElement(a)
 }
 
+// Namespaces of foreign elements as used by the HTML parser.
+const (
+	NamespaceHTML   = ""
+	NamespaceSVG    = "svg"
+	NamespaceMathML = "math"
+)
+
+// NewElementNS creates a new element node with the given namespace and tag name.
+// The tag name is lowercased and, for an SVG element, its case is adjusted
+// as the HTML parser does, e.g. "linearGradient" and "foreignObject".
+func NewElementNS(namespace, name string) *Element {
+	name = adjustTagName(namespace, name)
+	return &Element{
+		Type:      html.ElementNode,
+		DataAtom:  atom.Lookup([]byte(name)),
+		Data:      name,
+		Namespace: namespace,
+	}
+}
+
+// NewCustomElement creates a new autonomous custom element node with the given name.
+// It returns an error if the name is not a valid custom element name.
+func NewCustomElement(name string) (*Element, error) {
+	if err := ValidCustomElementName(name); err != nil {
+		return nil, err
+	}
+	return &Element{
+		Type: html.ElementNode,
+		Data: name,
+	}, nil
+}
+
+// CE is like NewCustomElement but panics if the name is not valid.
+func CE(name string) *Element {
+	e, err := NewCustomElement(name)
+	if err != nil {
+		log.Panicln(err)
+	}
+	return e
+}
+
+// reservedCustomElementNames are the hyphen-containing names used by SVG and MathML.
+var reservedCustomElementNames = []string{
+	"annotation-xml",
+	"color-profile",
+	"font-face",
+	"font-face-src",
+	"font-face-uri",
+	"font-face-format",
+	"font-face-name",
+	"missing-glyph",
+}
+
+// ValidCustomElementName checks the name as a valid custom element name of the HTML Standard.
+func ValidCustomElementName(name string) error {
+	if name == "" || name[0] < 'a' || 'z' < name[0] {
+		return fmt.Errorf("custom element name must start with a lowercase ASCII letter: %q", name)
+	}
+	if !strings.Contains(name, "-") {
+		return fmt.Errorf("custom element name must contain a hyphen: %q", name)
+	}
+	if slices.Contains(reservedCustomElementNames, name) {
+		return fmt.Errorf("custom element name is reserved: %q", name)
+	}
+	for _, c := range name {
+		if !isPCENChar(c) {
+			return fmt.Errorf("custom element name has invalid character %q: %q", c, name)
+		}
+	}
+	return nil
+}
+
+// isPCENChar reports whether c is a PCENChar of the custom element name production.
+func isPCENChar(c rune) bool {
+	switch {
+	case c == '-' || c == '.' || c == '_' || c == 0xB7:
+		return true
+	case '0' <= c && c <= '9', 'a' <= c && c <= 'z':
+		return true
+	case 0xC0 <= c && c <= 0xD6, 0xD8 <= c && c <= 0xF6, 0xF8 <= c && c <= 0x37D,
+		0x37F <= c && c <= 0x1FFF, 0x200C <= c && c <= 0x200D, 0x203F <= c && c <= 0x2040,
+		0x2070 <= c && c <= 0x218F, 0x2C00 <= c && c <= 0x2FEF, 0x3001 <= c && c <= 0xD7FF,
+		0xF900 <= c && c <= 0xFDCF, 0xFDF0 <= c && c <= 0xFFFD, 0x10000 <= c && c <= 0xEFFFF:
+		return true
+	}
+	return false
+}
+
 // T creates a new text node with the given text.
 func NewText(text ...string) *Text {
 	return &Text{
@@ -362,6 +534,14 @@ func RawT(text ...string) *RawText {
 	return NewRawText(text...)
 }
 
+// NewComment creates a new comment node with the given text.
+func NewComment(text ...string) *Comment {
+	return &Comment{
+		Type: html.CommentNode,
+		Data: strings.Join(text, ""),
+	}
+}
+
 type Selector css.Selector
 
 // SelectorMustParse parses the given CSS selector and panics if it fails.
@@ -382,6 +562,7 @@ func ParseHTML(s io.Reader) (*Document, error) {
 }
 
 // ParseHTMLFragment parses the HTML fragment with node context from the given reader.
+// Text and comment nodes in the fragment are also returned as *Element; use ParseFragment to get them typed.
 func ParseHTMLFragment(s io.Reader, node *Element) ([]*Element, error) {
 	n, err := html.ParseFragment(s, (*html.Node)(node))
 
@@ -392,6 +573,57 @@ func ParseHTMLFragment(s io.Reader, node *Element) ([]*Element, error) {
 	return nodes, err
 }
 
+// wrapNode returns the node as the haat type corresponding to its node type.
+func wrapNode(n *html.Node) Node {
+	switch n.Type {
+	case html.DocumentNode:
+		return (*Document)(n)
+	case html.ElementNode:
+		return (*Element)(n)
+	case html.TextNode:
+		return (*Text)(n)
+	case html.RawNode:
+		return (*RawText)(n)
+	case html.DoctypeNode:
+		return (*Doctype)(n)
+	case html.CommentNode:
+		return (*Comment)(n)
+	}
+	log.Panicln("no case match")
+	return nil
+}
+
+// ParseFragment parses the HTML fragment with node context from the given reader.
+// Each node is returned as *Element, *Text or *Comment according to its node type.
+func ParseFragment(r io.Reader, context *Element) ([]Node, error) {
+	n, err := html.ParseFragment(r, (*html.Node)(context))
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]Node, len(n))
+	for i := range len(n) {
+		nodes[i] = wrapNode(n[i])
+	}
+	return nodes, nil
+}
+
+// ParseFragmentString is like ParseFragment but parses the given string.
+func ParseFragmentString(s string, context *Element) ([]Node, error) {
+	return ParseFragment(strings.NewReader(s), context)
+}
+
+// AppendNodes appends the given nodes, such as the result of ParseFragment, to the children of the node.
+func (e *Element) AppendNodes(nodes ...Node) *Element {
+	for _, n := range nodes {
+		c, ok := n.(ElementChild)
+		if !ok {
+			log.Panicln("not an element child:", typeString(n.NodeType()))
+		}
+		e.AppendC(c)
+	}
+	return e
+}
+
 // HasRoot returns true if the node has the given root node as parent.
 func (e *Element) HasRoot(root *Element) bool {
 	for p := e.Parent; p != nil; p = p.Parent {
@@ -485,15 +717,23 @@ func (e *Element) Render(w io.Writer, checker ...Checker) error {
 }
 
 // GetAttr returns the value of the attribute with the given key.
+// A namespaced attribute is specified by its qualified name such as "xlink:href".
 func (e *Element) GetAttr(key string) string {
 	for _, a := range e.Attr {
-		if a.Key == key {
+		if qualifiedName(a) == key {
 			return a.Val
 		}
 	}
 	return ""
 }
 
+// hasAttr returns true if the node has the attribute with the given key.
+func (e *Element) hasAttr(key string) bool {
+	return slices.ContainsFunc(e.Attr, func(a html.Attribute) bool {
+		return qualifiedName(a) == key
+	})
+}
+
 // ID returns the value of the id attribute.
 func (e *Element) ID() string {
 	for _, a := range e.Attr {
@@ -505,40 +745,52 @@ func (e *Element) ID() string {
 }
 
 // Checker is a function that checks the node.
+// The built-in checkers return an *Issue with the source position of the offending element,
+// or the issues joined by errors.Join if there are more than one. Issues splits them.
 type Checker func(*Element) error
 
+// BuiltinCheckers are the checkers provided by haat, as run by the haat lint command.
+var BuiltinCheckers = []Checker{
+	IDDuplicateCheck,
+	IDMissingCheck,
+	IDHasBlankCheck,
+}
+
 // IDDuplicateCheck checks if the node has duplicate id attributes.
 func IDDuplicateCheck(e *Element) error {
+	var issues []*Issue
 	ids := map[string]struct{}{}
 	for _, e := range e.Query("[id]") {
 		id := e.ID()
 		if _, ok := ids[id]; ok {
-			return fmt.Errorf("duplicate id: %s", id)
+			issues = append(issues, NewIssue(e, "duplicate id: %s", id))
 		}
 		ids[id] = struct{}{}
 	}
-	return nil
+	return joinIssues(issues)
 }
 
 // IDMissingCheck checks if the node has id without value.
 func IDMissingCheck(e *Element) error {
+	var issues []*Issue
 	for _, e := range e.Query("[id]") {
 		if e.ID() == "" {
-			return fmt.Errorf("missing id")
+			issues = append(issues, NewIssue(e, "missing id"))
 		}
 	}
-	return nil
+	return joinIssues(issues)
 }
 
 // IDHasBlankCheck checks if the node has id with blank.
 func IDHasBlankCheck(e *Element) error {
+	var issues []*Issue
 	for _, e := range e.Query("[id]") {
 		id := e.ID()
 		if strings.Contains(id, " ") {
-			return fmt.Errorf("id has blank: %s", id)
+			issues = append(issues, NewIssue(e, "id has blank: %s", id))
 		}
 	}
-	return nil
+	return joinIssues(issues)
 }
 
 // typeString returns the string representation of the node type.
@@ -573,10 +825,15 @@ func dumpNode(n *html.Node, indent int, mark string) {
 }
 
 // DumpDocument prints the node to the standard output.
+//
+// Deprecated: Use Dump.
 func DumpDocument(d *Document, indent int, mark string) {
 	dumpNode((*html.Node)(d), indent, mark)
 }
 
+// DumpElement prints the node to the standard output.
+//
+// Deprecated: Use Dump.
 func DumpElement(e *Element, indent int, mark string) {
 	dumpNode((*html.Node)(e), indent, mark)
 }
@@ -595,7 +852,9 @@ func dumpNode2(n *html.Node, indent int, mark string) {
 	dumpNode2(n.NextSibling, indent, "S")
 }
 
-// DumpNode2 prints the node to the standard output.
+// DumpDocument2 prints the node with its attributes to the standard output.
+//
+// Deprecated: Use Dump.
 func DumpDocument2(d *Document, indent int, mark string) {
 	dumpNode2((*html.Node)(d), indent, mark)
 }
@@ -2,6 +2,7 @@ package haat
 
 import (
 	"bytes"
+	"slices"
 	"strings"
 	"testing"
 
@@ -131,3 +132,148 @@ func TestRawTest(t *testing.T) {
 		t.Errorf("got: %v\nwant: %v", actual, expected)
 	}
 }
+
+func TestNewElementNS(t *testing.T) {
+	svg := NewElementNS(NamespaceSVG, "svg").SetA(A("viewBox", "0 0 10 10"))
+	svg.AppendC(
+		NewElementNS(NamespaceSVG, "path").SetA(A("d", "M0 0L10 10")),
+		NewElementNS(NamespaceSVG, "use").SetA(A("href", "#a"), AttrXlinkHref("#b")),
+	)
+
+	var buf bytes.Buffer
+	if err := svg.Render(&buf); err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+
+	expected := `<svg viewBox="0 0 10 10"><path d="M0 0L10 10"></path><use href="#a" xlink:href="#b"></use></svg>`
+	actual := buf.String()
+	if actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+
+	use := svg.Query("[href]")[0]
+	if got := use.GetAttr("xlink:href"); got != "#b" {
+		t.Errorf("got: %v\nwant: %v", got, "#b")
+	}
+	use.SetA(A("xlink:href", "#c")).RemoveAttr("href")
+	if len(use.Attr) != 1 || use.Attr[0].Namespace != "xlink" || use.Attr[0].Val != "#c" {
+		t.Errorf("got: %v\nwant: %v", use.Attr, "xlink:href=#c")
+	}
+}
+
+func TestNewElementNSMatchesParser(t *testing.T) {
+	doc, err := ParseHTML(strings.NewReader(`<svg viewbox="0 0 1 1"><foreignObject refX="1"></foreignObject></svg><math definitionurl="u"></math>`))
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	parsed := doc.Query("[viewBox]")[0]
+	built := NewElementNS(NamespaceSVG, "SVG").SetA(A("VIEWBOX", "0 0 1 1"))
+	fo := NewElementNS(NamespaceSVG, "foreignobject").SetA(A("refx", "1"))
+	built.AppendC(fo)
+	math := NewElementNS(NamespaceMathML, "math").SetA(A("definitionURL", "u"))
+
+	for _, pair := range [][2]*Element{
+		{parsed, built},
+		{(*Element)(parsed.FirstChild), fo},
+		{doc.Query("[definitionURL]")[0], math},
+	} {
+		p, b := pair[0], pair[1]
+		if p.Data != b.Data || p.DataAtom != b.DataAtom || p.Namespace != b.Namespace {
+			t.Errorf("got: %v %v %v\nwant: %v %v %v", b.Data, b.DataAtom, b.Namespace, p.Data, p.DataAtom, p.Namespace)
+		}
+		if !slices.Equal(p.Attr, b.Attr) {
+			t.Errorf("got: %v\nwant: %v", b.Attr, p.Attr)
+		}
+	}
+
+	if built.RemoveAttr("viewBox"); len(built.Attr) != 0 {
+		t.Errorf("got: %v\nwant: %v", built.Attr, nil)
+	}
+}
+
+func TestNewCustomElement(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"my-element", false},
+		{"x-", false},
+		{"math-α", false},
+		{"myelement", true},
+		{"My-element", true},
+		{"my-Element", true},
+		{"1-element", true},
+		{"font-face", true},
+		{"my element", true},
+	}
+
+	for _, tt := range tests {
+		e, err := NewCustomElement(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewCustomElement(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err == nil && e.Data != tt.name {
+			t.Errorf("got: %v\nwant: %v", e.Data, tt.name)
+		}
+	}
+}
+
+func TestSetAOrder(t *testing.T) {
+	ht, err := ParseHTMLFragment(strings.NewReader(`<p xxx="yyy" id="foo" class="c" xxx="zzz">Hello</p>`), NewElement(atom.Div))
+	if err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+	ht[0].SetAOrder(AttrOrderSource, NewAttribute("id", "bar"), NewAttribute("abc", "1"), NewAttribute("xxx", "aaa"))
+
+	var buf bytes.Buffer
+	if err := ht[0].Render(&buf); err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+
+	expected := `<p xxx="aaa" id="bar" class="c" abc="1">Hello</p>`
+	actual := buf.String()
+	if actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+
+	ht[0].SetBoolAOrder(AttrOrderSource, "hidden", true)
+	expected = `<p xxx="aaa" id="bar" class="c" abc="1" hidden="">Hello</p>`
+	buf.Reset()
+	if err := ht[0].Render(&buf); err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+	if actual := buf.String(); actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+}
+
+func TestParseFragment(t *testing.T) {
+	nodes, err := ParseFragmentString(`Hello <b>x</b><!-- c -->`, NewElement(atom.Div))
+	if err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+	if len(nodes) != 3 {
+		t.Fatalf("got: %v\nwant: %v", len(nodes), 3)
+	}
+	if _, ok := nodes[0].(*Text); !ok {
+		t.Errorf("got: %T\nwant: %v", nodes[0], "*Text")
+	}
+	if _, ok := nodes[1].(*Element); !ok {
+		t.Errorf("got: %T\nwant: %v", nodes[1], "*Element")
+	}
+	if _, ok := nodes[2].(*Comment); !ok {
+		t.Errorf("got: %T\nwant: %v", nodes[2], "*Comment")
+	}
+
+	p := NewElement(atom.P).AppendNodes(nodes...)
+	var buf bytes.Buffer
+	if err := p.Render(&buf); err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+
+	expected := `<p>Hello <b>x</b><!-- c --></p>`
+	actual := buf.String()
+	if actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+}
@@ -0,0 +1,243 @@
+// Package haathttp serves haat documents over net/http.
+package haathttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/turutcrane/haat"
+	"golang.org/x/net/html/atom"
+)
+
+// ContentType is the Content-Type header value of the rendered documents.
+const ContentType = "text/html; charset=utf-8"
+
+// BuildFunc builds the document for the request.
+type BuildFunc func(r *http.Request) (*haat.Document, error)
+
+// ErrorPageFunc builds the document of the error page for the status code.
+type ErrorPageFunc func(r *http.Request, status int, err error) *haat.Document
+
+// Error is an error with the HTTP status code.
+// A BuildFunc returns it to respond with a status other than 500 Internal Server Error.
+type Error struct {
+	Status int
+	Err    error
+}
+
+// NewError creates a new error with the status code.
+func NewError(status int, err error) *Error {
+	return &Error{Status: status, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return http.StatusText(e.Status)
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// options are the options of Handler and Render.
+type options struct {
+	checkers  []haat.Checker
+	etag      bool
+	errorPage ErrorPageFunc
+	fragments bool
+	oob       []string
+}
+
+// Option configures Handler and Render.
+type Option func(*options)
+
+// WithCheckers sets the checkers applied before rendering,
+// including to the out of band elements of a fragment.
+// A checker failure responds with 500 Internal Server Error.
+func WithCheckers(checkers ...haat.Checker) Option {
+	return func(o *options) {
+		o.checkers = append(o.checkers, checkers...)
+	}
+}
+
+// WithETag enables the ETag header generated from the rendered bytes
+// and 304 Not Modified responses to GET and HEAD requests with a matching If-None-Match.
+func WithETag() Option {
+	return func(o *options) {
+		o.etag = true
+	}
+}
+
+// WithErrorPage sets the function which builds the error pages.
+func WithErrorPage(f ErrorPageFunc) Option {
+	return func(o *options) {
+		o.errorPage = f
+	}
+}
+
+// WithFragments enables the partial rendering for htmx and Turbo-style requests.
+// When the request has the HX-Target header or the fragment query parameter,
+// only the element with that id is rendered, followed by the elements that match
+// the oob selectors with the hx-swap-oob attribute.
+func WithFragments(oob ...string) Option {
+	return func(o *options) {
+		o.fragments = true
+		o.oob = append(o.oob, oob...)
+	}
+}
+
+// FragmentTarget returns the id of the element requested by the HX-Target header
+// or the fragment query parameter, or "" for a full page request.
+func FragmentTarget(r *http.Request) string {
+	if id := r.Header.Get("HX-Target"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("fragment")
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{errorPage: DefaultErrorPage}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// DefaultErrorPage builds a simple error page which shows the status text.
+// The error message is not shown to avoid leaking internal details.
+func DefaultErrorPage(r *http.Request, status int, err error) *haat.Document {
+	text := strconv.Itoa(status) + " " + http.StatusText(status)
+	return haat.NewDocument(
+		haat.E(atom.Head).C(haat.E(atom.Title).C(haat.T(text))),
+		haat.E(atom.Body).C(haat.E(atom.H1).C(haat.T(text))),
+	)
+}
+
+// handler is the http.Handler returned by Handler.
+type handler struct {
+	build BuildFunc
+	opts  *options
+}
+
+// Handler returns an http.Handler which responds with the document built by f.
+// The document is rendered to a buffer first, so that an error is responded
+// with an error page instead of a partial page.
+func Handler(f BuildFunc, opts ...Option) http.Handler {
+	return &handler{build: f, opts: newOptions(opts)}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	doc, err := h.build(r)
+	if err != nil {
+		serveError(w, r, h.opts, err)
+		return
+	}
+	serve(w, r, h.opts, doc)
+}
+
+// Render renders the document to the response in the same way as Handler.
+func Render(w http.ResponseWriter, r *http.Request, doc *haat.Document, opts ...Option) {
+	serve(w, r, newOptions(opts), doc)
+}
+
+func serve(w http.ResponseWriter, r *http.Request, o *options, doc *haat.Document) {
+	var buf bytes.Buffer
+	if err := render(&buf, r, o, doc); err != nil {
+		serveError(w, r, o, err)
+		return
+	}
+	write(w, r, o, http.StatusOK, buf.Bytes())
+}
+
+// render renders the whole document or the requested fragment.
+func render(buf *bytes.Buffer, r *http.Request, o *options, doc *haat.Document) error {
+	if !o.fragments {
+		return doc.Render(buf, o.checkers...)
+	}
+	target := FragmentTarget(r)
+	if target == "" {
+		return doc.Render(buf, o.checkers...)
+	}
+	e := doc.ElementByID(target)
+	if e == nil {
+		return NewError(http.StatusNotFound, fmt.Errorf("fragment not found: %s", target))
+	}
+	if err := e.Render(buf, o.checkers...); err != nil {
+		return err
+	}
+	for _, s := range o.oob {
+		for _, e := range doc.Query(s) {
+			for _, c := range o.checkers {
+				if err := c(e); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return doc.RenderOOB(buf, o.oob...)
+}
+
+// serveError responds with the error page for the error.
+func serveError(w http.ResponseWriter, r *http.Request, o *options, err error) {
+	status := http.StatusInternalServerError
+	var he *Error
+	if errors.As(err, &he) {
+		status = he.Status
+	}
+
+	var buf bytes.Buffer
+	if page := o.errorPage(r, status, err); page == nil || page.Render(&buf) != nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	write(w, r, &options{}, status, buf.Bytes())
+}
+
+// write writes the rendered bytes with the headers.
+func write(w http.ResponseWriter, r *http.Request, o *options, status int, body []byte) {
+	header := w.Header()
+	header.Set("Content-Type", ContentType)
+	if o.fragments {
+		header.Add("Vary", "HX-Target")
+	}
+	if o.etag && status == http.StatusOK {
+		etag := ETag(body)
+		header.Set("ETag", etag)
+		// If-None-Match on other methods is a precondition, which is not checked here.
+		if (r.Method == http.MethodGet || r.Method == http.MethodHead) && matchETag(r.Header.Get("If-None-Match"), etag) {
+			header.Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// ETag returns a strong entity tag of the body.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// matchETag reports whether the If-None-Match header matches the entity tag by weak comparison.
+func matchETag(ifNoneMatch, etag string) bool {
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
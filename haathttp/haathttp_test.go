@@ -0,0 +1,141 @@
+package haathttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/turutcrane/haat"
+	"golang.org/x/net/html/atom"
+)
+
+func page(id ...string) *haat.Document {
+	body := haat.E(atom.Body)
+	for _, i := range id {
+		body.AppendC(haat.E(atom.P).SetA(haat.AttrID(i)).C(haat.T("Hello")))
+	}
+	return haat.NewDocument(haat.E(atom.Head), body)
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler(func(r *http.Request) (*haat.Document, error) {
+		return page("a"), nil
+	}, WithETag())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	expected := `<!DOCTYPE html><html><head></head><body><p id="a">Hello</p></body></html>`
+	if rec.Code != http.StatusOK || rec.Body.String() != expected {
+		t.Errorf("got: %v %v\nwant: %v %v", rec.Code, rec.Body.String(), http.StatusOK, expected)
+	}
+	if actual := rec.Header().Get("Content-Type"); actual != ContentType {
+		t.Errorf("got: %v\nwant: %v", actual, ContentType)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag != ETag([]byte(expected)) {
+		t.Errorf("got: %v\nwant: %v", etag, ETag([]byte(expected)))
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", `"x", W/`+etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("got: %v %v\nwant: %v", rec.Code, rec.Body.String(), http.StatusNotModified)
+	}
+
+	req = httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != expected {
+		t.Errorf("got: %v %v\nwant: %v %v", rec.Code, rec.Body.String(), http.StatusOK, expected)
+	}
+}
+
+func TestHandlerError(t *testing.T) {
+	tests := []struct {
+		name   string
+		build  BuildFunc
+		status int
+	}{
+		{"checker failure", func(r *http.Request) (*haat.Document, error) { return page("a", "a"), nil }, http.StatusInternalServerError},
+		{"build error", func(r *http.Request) (*haat.Document, error) { return nil, errors.New("db down") }, http.StatusInternalServerError},
+		{"status error", func(r *http.Request) (*haat.Document, error) { return nil, NewError(http.StatusNotFound, nil) }, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotErr error
+			h := Handler(tt.build, WithCheckers(haat.IDDuplicateCheck), WithErrorPage(func(r *http.Request, status int, err error) *haat.Document {
+				gotErr = err
+				return DefaultErrorPage(r, status, err)
+			}))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+			if rec.Code != tt.status || gotErr == nil {
+				t.Errorf("got: %v %v\nwant: %v", rec.Code, gotErr, tt.status)
+			}
+			if strings.Contains(rec.Body.String(), "Hello") || !strings.Contains(rec.Body.String(), http.StatusText(tt.status)) {
+				t.Errorf("got: %v\nwant: error page", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlerFragments(t *testing.T) {
+	h := Handler(func(r *http.Request) (*haat.Document, error) {
+		return page("a", "b", "c"), nil
+	}, WithFragments("#c"))
+
+	tests := []struct {
+		name   string
+		target string
+		url    string
+		status int
+		want   string
+	}{
+		{"full page", "", "/", http.StatusOK, `<!DOCTYPE html><html><head></head><body><p id="a">Hello</p><p id="b">Hello</p><p id="c">Hello</p></body></html>`},
+		{"hx-target", "b", "/", http.StatusOK, `<p id="b">Hello</p><p hx-swap-oob="true" id="c">Hello</p>`},
+		{"query", "", "/?fragment=a", http.StatusOK, `<p id="a">Hello</p><p hx-swap-oob="true" id="c">Hello</p>`},
+		{"not found", "x", "/", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			if tt.target != "" {
+				req.Header.Set("HX-Target", tt.target)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("got: %v\nwant: %v", rec.Code, tt.status)
+			}
+			if tt.want != "" && rec.Body.String() != tt.want {
+				t.Errorf("got: %v\nwant: %v", rec.Body.String(), tt.want)
+			}
+		})
+	}
+
+	h = Handler(func(r *http.Request) (*haat.Document, error) {
+		return page("a", "c"), nil
+	}, WithFragments("#c"), WithCheckers(func(e *haat.Element) error {
+		if e.ID() == "c" {
+			return errors.New("bad oob")
+		}
+		return nil
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("HX-Target", "a")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("got: %v\nwant: %v", rec.Code, http.StatusInternalServerError)
+	}
+}
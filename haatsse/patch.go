@@ -0,0 +1,189 @@
+// Package haatsse streams DOM patches of haat elements as Server-Sent Events.
+package haatsse
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"github.com/turutcrane/haat"
+	"golang.org/x/net/html"
+)
+
+// Op is the operation of a patch.
+type Op string
+
+const (
+	// OpReplace replaces the target node by HTML.
+	OpReplace Op = "replace-node"
+	// OpSetAttr sets the attribute Name of the target element to Value.
+	OpSetAttr Op = "set-attr"
+	// OpRemoveAttr removes the attribute Name of the target element.
+	OpRemoveAttr Op = "remove-attr"
+	// OpSetText sets the data of the target text or comment node to Value.
+	OpSetText Op = "set-text"
+	// OpInsertBefore inserts HTML into the target element before the child at Index,
+	// or appends it when Index is -1.
+	OpInsertBefore Op = "insert-before"
+	// OpRemove removes the target node.
+	OpRemove Op = "remove-node"
+)
+
+// Patch is an operation on the node addressed by the id of the nearest element
+// and the path of child node indexes from it.
+type Patch struct {
+	Op    Op     `json:"op"`
+	ID    string `json:"id"`
+	Path  []int  `json:"path"`
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
+	HTML  string `json:"html,omitempty"`
+	Index int    `json:"index,omitempty"`
+}
+
+// target is the address of a node.
+type target struct {
+	id   string
+	path []int
+}
+
+func (t target) child(i int, n *html.Node) target {
+	if n.Type == html.ElementNode {
+		if id := (*haat.Element)(n).ID(); id != "" {
+			return target{id: id}
+		}
+	}
+	return target{id: t.id, path: append(slices.Clip(t.path), i)}
+}
+
+func (t target) patch(op Op) Patch {
+	return Patch{Op: op, ID: t.id, Path: t.path}
+}
+
+// Diff returns the patches which change the element old to the element new.
+// The root elements must have the same id, which addresses the patches on the client.
+// Children are compared by position, and the patches are applied in order.
+// Adjacent text nodes are compared as one, as the browser parses them.
+func Diff(old, new *haat.Element) ([]Patch, error) {
+	if old.ID() == "" || old.ID() != new.ID() {
+		return nil, fmt.Errorf("root elements must have the same id: %q, %q", old.ID(), new.ID())
+	}
+	d := &differ{}
+	d.node((*html.Node)(old), (*html.Node)(new), target{id: old.ID()})
+	return d.patches, d.err
+}
+
+type differ struct {
+	patches []Patch
+	err     error
+}
+
+func (d *differ) add(p Patch) {
+	d.patches = append(d.patches, p)
+}
+
+func (d *differ) render(n *html.Node) string {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, n); err != nil && d.err == nil {
+		d.err = err
+	}
+	return buf.String()
+}
+
+// sameKind reports whether the node a can be patched into b without replacing it.
+func sameKind(a, b *html.Node) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case html.ElementNode:
+		return a.Data == b.Data && a.Namespace == b.Namespace &&
+			(*haat.Element)(a).ID() == (*haat.Element)(b).ID()
+	case html.RawNode:
+		return a.Data == b.Data
+	}
+	return true
+}
+
+// children returns the child nodes as the browser has them after parsing the rendered HTML:
+// adjacent text nodes are merged and empty text nodes are dropped.
+// The client normalizes the patched elements to keep the same child indexes.
+func children(n *html.Node) []*html.Node {
+	var cs []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			if c.Data == "" {
+				continue
+			}
+			if last := len(cs) - 1; last >= 0 && cs[last].Type == html.TextNode {
+				cs[last] = &html.Node{Type: html.TextNode, Data: cs[last].Data + c.Data}
+				continue
+			}
+		}
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+func (d *differ) node(a, b *html.Node, t target) {
+	if !sameKind(a, b) {
+		p := t.patch(OpReplace)
+		p.HTML = d.render(b)
+		d.add(p)
+		return
+	}
+	switch a.Type {
+	case html.TextNode, html.CommentNode:
+		if a.Data != b.Data {
+			p := t.patch(OpSetText)
+			p.Value = b.Data
+			d.add(p)
+		}
+		return
+	case html.ElementNode:
+		d.attrs(a, b, t)
+	default:
+		return
+	}
+
+	as, bs := children(a), children(b)
+	n := min(len(as), len(bs))
+	for i := range n {
+		d.node(as[i], bs[i], t.child(i, as[i]))
+	}
+	for i := len(as) - 1; i >= n; i-- {
+		d.add(t.child(i, as[i]).patch(OpRemove))
+	}
+	for _, c := range bs[n:] {
+		p := t.patch(OpInsertBefore)
+		p.Index = -1
+		p.HTML = d.render(c)
+		d.add(p)
+	}
+}
+
+func (d *differ) attrs(a, b *html.Node, t target) {
+	old := map[string]string{}
+	for _, at := range a.Attr {
+		old[haat.Attribute(at).QualifiedName()] = at.Val
+	}
+	seen := map[string]bool{}
+	for _, at := range b.Attr {
+		name := haat.Attribute(at).QualifiedName()
+		seen[name] = true
+		if v, ok := old[name]; !ok || v != at.Val {
+			p := t.patch(OpSetAttr)
+			p.Name = name
+			p.Value = at.Val
+			d.add(p)
+		}
+	}
+	for _, at := range a.Attr {
+		if name := haat.Attribute(at).QualifiedName(); !seen[name] {
+			seen[name] = true
+			p := t.patch(OpRemoveAttr)
+			p.Name = name
+			d.add(p)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package haatsse
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/turutcrane/haat"
+	"golang.org/x/net/html/atom"
+)
+
+func fragment(t *testing.T, s string) *haat.Element {
+	t.Helper()
+	ht, err := haat.ParseHTMLFragment(strings.NewReader(s), haat.NewElement(atom.Body))
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	return ht[0]
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want string
+	}{
+		{
+			"no change",
+			`<div id="d"><p>a</p></div>`,
+			`<div id="d"><p>a</p></div>`,
+			`null`,
+		},
+		{
+			"text and attributes",
+			`<div id="d" class="a" title="t"><p>a</p><span id="s">1</span></div>`,
+			`<div id="d" class="b"><p>b</p><span id="s" hidden>1</span></div>`,
+			`[{"op":"set-attr","id":"d","path":null,"name":"class","value":"b"},` +
+				`{"op":"remove-attr","id":"d","path":null,"name":"title"},` +
+				`{"op":"set-text","id":"d","path":[0,0],"value":"b"},` +
+				`{"op":"set-attr","id":"s","path":null,"name":"hidden"}]`,
+		},
+		{
+			"replace insert remove",
+			`<ul id="l"><li>1</li><li>2</li><li>3</li></ul>`,
+			`<ul id="l"><b>1</b></ul>`,
+			`[{"op":"replace-node","id":"l","path":[0],"html":"\u003cb\u003e1\u003c/b\u003e"},` +
+				`{"op":"remove-node","id":"l","path":[2]},` +
+				`{"op":"remove-node","id":"l","path":[1]}]`,
+		},
+		{
+			"append",
+			`<ul id="l"><li>1</li></ul>`,
+			`<ul id="l"><li>1</li><li>2</li></ul>`,
+			`[{"op":"insert-before","id":"l","path":null,"html":"\u003cli\u003e2\u003c/li\u003e","index":-1}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patches, err := Diff(fragment(t, tt.old), fragment(t, tt.new))
+			if err != nil {
+				t.Fatalf("got: %v\nwant: %v", err, nil)
+			}
+			actual, _ := json.Marshal(patches)
+			if string(actual) != tt.want {
+				t.Errorf("got: %s\nwant: %s", actual, tt.want)
+			}
+		})
+	}
+
+	// the browser has one text node for the adjacent text nodes
+	old := haat.E(atom.P).SetA(haat.AttrID("p")).C(haat.T("a"), haat.T(""), haat.T("b"), haat.E(atom.I).C(haat.T("1")))
+	new := haat.E(atom.P).SetA(haat.AttrID("p")).C(haat.T("ab"), haat.E(atom.I).C(haat.T("2")))
+	patches, err := Diff(old, new)
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	expected := `[{"op":"set-text","id":"p","path":[1,0],"value":"2"}]`
+	if actual, _ := json.Marshal(patches); string(actual) != expected {
+		t.Errorf("got: %s\nwant: %s", actual, expected)
+	}
+
+	if _, err := Diff(fragment(t, `<p id="a"></p>`), fragment(t, `<p id="b"></p>`)); err == nil {
+		t.Errorf("got: %v\nwant: error", err)
+	}
+}
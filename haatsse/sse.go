@@ -0,0 +1,175 @@
+package haatsse
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/turutcrane/haat"
+	"github.com/turutcrane/haat/js"
+)
+
+// Broadcaster sends the patches between the states of an element
+// to the connected clients as "patch" events of Server-Sent Events.
+type Broadcaster struct {
+	mu      sync.Mutex
+	current *haat.Element
+	clients map[chan []byte]struct{}
+}
+
+// NewBroadcaster creates a new broadcaster with the initial state of the element.
+// The element must have an id to address it on the clients.
+func NewBroadcaster(initial *haat.Element) *Broadcaster {
+	return &Broadcaster{
+		current: initial.Clone(),
+		clients: map[chan []byte]struct{}{},
+	}
+}
+
+// Update sends the patches from the current state to the next state to the clients.
+// The next state is cloned, so the caller can keep modifying it.
+func (b *Broadcaster) Update(next *haat.Element) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	patches, err := Diff(b.current, next)
+	if err != nil {
+		return err
+	}
+	b.current = next.Clone()
+	if len(patches) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(patches)
+	if err != nil {
+		return err
+	}
+	for c := range b.clients {
+		select {
+		case c <- data:
+		default:
+			// A slow client is disconnected and gets the whole element when it reconnects.
+			delete(b.clients, c)
+			close(c)
+		}
+	}
+	return nil
+}
+
+// subscribe registers a new client and returns its channel with the patch replacing the whole element.
+func (b *Broadcaster) subscribe() (chan []byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	html, err := b.current.OuterHTML()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal([]Patch{{Op: OpReplace, ID: b.current.ID(), HTML: html}})
+	if err != nil {
+		return nil, err
+	}
+	c := make(chan []byte, 16)
+	c <- data
+	b.clients[c] = struct{}{}
+	return c, nil
+}
+
+func (b *Broadcaster) unsubscribe(c chan []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.clients[c]; ok {
+		delete(b.clients, c)
+		close(c)
+	}
+}
+
+// ServeHTTP streams the patches to the client until the request is canceled.
+// The first event replaces the whole element with the current state.
+func (b *Broadcaster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	c, err := b.subscribe()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer b.unsubscribe(c)
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data, ok := <-c:
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: patch\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// clientScript is the function which applies the patches received from haatPatchURL.
+// It connects once for each URL even if the script is included more than once.
+const clientScript = `(haatPatchURL) => {
+  const connected = window.haatPatchURLs || (window.haatPatchURLs = new Set());
+  if (connected.has(haatPatchURL)) return;
+  connected.add(haatPatchURL);
+  const resolve = (p) => {
+    let n = document.getElementById(p.id);
+    for (const i of p.path || []) {
+      if (!n) return null;
+      n = n.childNodes[i];
+    }
+    return n;
+  };
+  const fragment = (html) => {
+    const t = document.createElement("template");
+    t.innerHTML = html;
+    return t.content;
+  };
+  const apply = (p) => {
+    const n = resolve(p);
+    if (!n) return;
+    switch (p.op) {
+    case "replace-node": n.replaceWith(fragment(p.html || "")); break;
+    case "set-attr": n.setAttribute(p.name, p.value || ""); break;
+    case "remove-attr": n.removeAttribute(p.name); break;
+    case "set-text": n.data = p.value || ""; break;
+    case "insert-before": {
+      const i = p.index === undefined ? 0 : p.index;
+      n.insertBefore(fragment(p.html || ""), i < 0 ? null : n.childNodes[i] || null);
+      break;
+    }
+    case "remove-node": n.remove(); break;
+    }
+  };
+  const es = new EventSource(haatPatchURL);
+  es.addEventListener("patch", (e) => {
+    const patches = JSON.parse(e.data);
+    patches.forEach(apply);
+    // merge the text nodes split by the patches, as the server does to address the nodes
+    for (const p of patches) {
+      const n = document.getElementById(p.id);
+      if (n) n.normalize();
+    }
+  });
+}`
+
+// ClientScript returns the JavaScript which connects to the url and applies the patches.
+// It is intended to be put in a <script> element with haat.RawT.
+func ClientScript(url string) string {
+	lit, _ := js.Literal(url) // a string is always encoded
+	return "(" + clientScript + ")(" + lit + ");"
+}
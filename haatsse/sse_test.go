@@ -0,0 +1,60 @@
+package haatsse
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/turutcrane/haat"
+)
+
+func TestBroadcaster(t *testing.T) {
+	state := fragment(t, `<p id="count">0</p>`)
+	b := NewBroadcaster(state)
+	srv := httptest.NewServer(b)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("got: %v\nwant: %v", ct, "text/event-stream")
+	}
+
+	state.C(haat.T("1"))
+	if err := b.Update(state); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+
+	r := bufio.NewReader(res.Body)
+	var events []string
+	for len(events) < 2 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("got: %v\nwant: %v", err, nil)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			events = append(events, strings.TrimSpace(data))
+		}
+	}
+
+	expected := []string{
+		`[{"op":"replace-node","id":"count","path":null,"html":"\u003cp id=\"count\"\u003e0\u003c/p\u003e"}]`,
+		`[{"op":"set-text","id":"count","path":[0],"value":"1"}]`,
+	}
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got: %v\nwant: %v", events, expected)
+	}
+}
+
+func TestClientScript(t *testing.T) {
+	script := ClientScript("/events?x=</script>")
+	expected := `)("/events?x\u003D\u003C/script\u003E");`
+	if !strings.HasPrefix(script, "((haatPatchURL) => {") || !strings.HasSuffix(script, expected) {
+		t.Errorf("got: %v\nwant: %v", script, expected)
+	}
+}
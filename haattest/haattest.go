@@ -0,0 +1,164 @@
+// Package haattest provides assertions and golden files for tests of pages built with haat.
+package haattest
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/turutcrane/haat"
+	"golang.org/x/net/html/atom"
+)
+
+// UpdateEnv is the environment variable which makes Golden rewrite the golden files,
+// as in "HAATTEST_UPDATE=1 go test".
+const UpdateEnv = "HAATTEST_UPDATE"
+
+// UpdateFlag is the name of the flag which also makes Golden rewrite the golden files,
+// as in "go test -update". haattest defines the flag,
+// so the test packages which import haattest must not define their own.
+const UpdateFlag = "update"
+
+var update = flag.Bool(UpdateFlag, false, "rewrite the golden files of haattest.Golden")
+
+// updating reports whether the update environment variable or the update flag is set.
+// They are read when Golden is called, after the test flags are parsed.
+func updating() bool {
+	if v, err := strconv.ParseBool(os.Getenv(UpdateEnv)); err == nil && v {
+		return true
+	}
+	return *update
+}
+
+// updateHint tells how to rewrite the golden files.
+const updateHint = "run with -" + UpdateFlag + " or " + UpdateEnv + "=1 to"
+
+// diffOptions ignores insignificant whitespace; attribute order is always ignored by haat.Diff.
+var diffOptions = &haat.DiffOptions{IgnoreWhitespace: true}
+
+// parseWant parses the expected HTML as a document if got is a document,
+// otherwise as a fragment in a template element, which accepts any element.
+func parseWant(got haat.Node, want string) (haat.Node, error) {
+	if _, ok := got.(*haat.Document); ok {
+		return haat.ParseHTML(strings.NewReader(want))
+	}
+	nodes, err := haat.ParseFragmentString(want, haat.E(atom.Template))
+	if err != nil {
+		return nil, err
+	}
+	var roots []haat.Node
+	for _, n := range nodes {
+		if t, ok := n.(*haat.Text); ok && strings.TrimSpace(t.Data) == "" {
+			continue
+		}
+		roots = append(roots, n)
+	}
+	if len(roots) != 1 {
+		return nil, errors.New("want must have a single root node, but has " + strconv.Itoa(len(roots)))
+	}
+	return roots[0], nil
+}
+
+// diffHTML returns the changes from want to got formatted one per line.
+func diffHTML(got haat.Node, want string) (string, error) {
+	w, err := parseWant(got, want)
+	if err != nil {
+		return "", err
+	}
+	return haat.FormatChanges(haat.Diff(w, got, diffOptions)), nil
+}
+
+// AssertHTML reports an error if the node is not structurally equal to the HTML want.
+// Attribute order and whitespace-only text nodes are ignored, and whitespace in text is collapsed.
+// want is parsed as a document if got is a *haat.Document, otherwise as a fragment with a single root.
+func AssertHTML(t testing.TB, got haat.Node, want string) {
+	t.Helper()
+	changes, err := diffHTML(got, want)
+	if err != nil {
+		t.Fatalf("parse want: %v", err)
+	}
+	if changes != "" {
+		t.Errorf("HTML mismatch (- want, + got):\n%s", changes)
+	}
+}
+
+// Renderer is a node which can be rendered, such as *haat.Document and *haat.Element.
+type Renderer interface {
+	haat.Node
+	Render(w io.Writer, checker ...haat.Checker) error
+}
+
+// GoldenPath returns the path of the golden file of the given name.
+func GoldenPath(name string) string {
+	return filepath.Join("testdata", name+".golden.html")
+}
+
+// Golden compares the rendered node with the golden file testdata/<name>.golden.html.
+// With the -update flag or HAATTEST_UPDATE=1, the golden file is written instead.
+func Golden(t testing.TB, name string, n Renderer) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := n.Render(&buf); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	path := GoldenPath(name)
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("update golden: %v", err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatalf("update golden: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("golden file %s does not exist; %s create it", path, updateHint)
+	} else if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	if bytes.Equal(buf.Bytes(), want) {
+		return
+	}
+	changes, err := diffHTML(n, string(want))
+	if err != nil || changes == "" {
+		t.Errorf("golden file %s mismatch (%s accept):\ngot:  %s\nwant: %s", path, updateHint, buf.String(), want)
+		return
+	}
+	t.Errorf("golden file %s mismatch (%s accept; - want, + got):\n%s", path, updateHint, changes)
+}
+
+// Querier is a node which can be queried by a selector, such as *haat.Document and *haat.Element.
+type Querier interface {
+	Query(selector string) []*haat.Element
+}
+
+// AssertText reports an error if the selector does not match exactly one element
+// or the rendered text of the element with whitespace collapsed is not want.
+func AssertText(t testing.TB, q Querier, selector, want string) {
+	t.Helper()
+	elements := q.Query(selector)
+	if len(elements) != 1 {
+		t.Errorf("%s: got %d elements\nwant: 1", selector, len(elements))
+		return
+	}
+	if got := elements[0].InnerText(); got != want {
+		t.Errorf("%s: got: %q\nwant: %q", selector, got, want)
+	}
+}
+
+// AssertCount reports an error if the selector does not match n elements.
+func AssertCount(t testing.TB, q Querier, selector string, n int) {
+	t.Helper()
+	if got := len(q.Query(selector)); got != n {
+		t.Errorf("%s: got %d elements\nwant: %d", selector, got, n)
+	}
+}
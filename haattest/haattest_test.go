@@ -0,0 +1,134 @@
+package haattest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/turutcrane/haat"
+	"golang.org/x/net/html/atom"
+)
+
+// recorder records the failures instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, a ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, a...))
+}
+
+func (r *recorder) Fatalf(format string, a ...any) {
+	r.Errorf(format, a...)
+	r.fatal = true
+}
+
+func page() *haat.Document {
+	return haat.NewDocument(
+		haat.E(atom.Head).C(haat.E(atom.Title).C(haat.T("Hello haat"))),
+		haat.E(atom.Body).C(
+			haat.E(atom.Span).SetA(haat.AttrID("pkgname"), haat.A("class", "name")).C(haat.T("haat")),
+			haat.E(atom.Ul).C(
+				haat.E(atom.Li).C(haat.T("1")),
+				haat.E(atom.Li).C(haat.T("2")),
+				haat.E(atom.Li).C(haat.T("3")),
+			),
+		),
+	)
+}
+
+func TestAssertHTML(t *testing.T) {
+	doc := page()
+	AssertHTML(t, doc, `<!DOCTYPE html>
+<html>
+  <head><title>Hello haat</title></head>
+  <body>
+    <span class="name" id="pkgname">haat</span>
+    <ul> <li>1</li> <li>2</li> <li>3</li> </ul>
+  </body>
+</html>`)
+	AssertHTML(t, doc.Query("li")[0], ` <li>1</li> `)
+
+	r := &recorder{TB: t}
+	AssertHTML(r, doc.Query("span")[0], `<span id="pkgname">go</span>`)
+	expected := `HTML mismatch (- want, + got):
++ /span@class: "name"
+~ /span/text(): "go" -> "haat"`
+	if len(r.errors) != 1 || r.errors[0] != expected {
+		t.Errorf("got: %v\nwant: %v", r.errors, expected)
+	}
+
+	r = &recorder{TB: t}
+	AssertHTML(r, doc.Query("li")[0], `<li>1</li><li>2</li>`)
+	if !r.fatal {
+		t.Errorf("got: %v\nwant: fatal for multiple roots", r.errors)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	Golden(t, "page", page())
+	if updating() {
+		return
+	}
+
+	doc := page()
+	doc.Query("li")[2].SetText("three")
+	r := &recorder{TB: t}
+	Golden(r, "page", doc)
+	if len(r.errors) != 1 || !strings.HasSuffix(r.errors[0], `~ /html/body/ul/li[3]/text(): "3" -> "three"`) {
+		t.Errorf("got: %v\nwant: a text change of li[3]", r.errors)
+	}
+
+	r = &recorder{TB: t}
+	Golden(r, "missing", doc)
+	if !r.fatal || !strings.Contains(r.errors[0], UpdateEnv+"=1") {
+		t.Errorf("got: %v\nwant: fatal for missing golden file", r.errors)
+	}
+}
+
+func TestUpdating(t *testing.T) {
+	if *update || os.Getenv(UpdateEnv) != "" {
+		t.Skip("updating the golden files")
+	}
+	if updating() {
+		t.Errorf("got: %v\nwant: %v", true, false)
+	}
+
+	t.Setenv(UpdateEnv, "1")
+	if !updating() {
+		t.Errorf("%s=1: got: %v\nwant: %v", UpdateEnv, false, true)
+	}
+	t.Setenv(UpdateEnv, "")
+
+	defer flag.Set(UpdateFlag, "false")
+	flag.Set(UpdateFlag, "true")
+	if !updating() {
+		t.Errorf("-%s: got: %v\nwant: %v", UpdateFlag, false, true)
+	}
+}
+
+func TestAssertTextCount(t *testing.T) {
+	doc := page()
+	AssertText(t, doc, "#pkgname", "haat")
+	AssertCount(t, doc, "li", 3)
+	AssertCount(t, doc.Query("ul")[0], "li", 3)
+
+	r := &recorder{TB: t}
+	AssertText(r, doc, "li", "1")
+	AssertText(r, doc, "#pkgname", "go")
+	AssertCount(r, doc, "li", 2)
+	expected := []string{
+		"li: got 3 elements\nwant: 1",
+		"#pkgname: got: \"haat\"\nwant: \"go\"",
+		"li: got 3 elements\nwant: 2",
+	}
+	if strings.Join(r.errors, "|") != strings.Join(expected, "|") {
+		t.Errorf("got: %q\nwant: %q", r.errors, expected)
+	}
+}
@@ -0,0 +1,70 @@
+package js
+
+import (
+	"html"
+	"strings"
+)
+
+// Builder accumulates JavaScript statements and keeps the first error.
+// After an error, the following statements are ignored.
+type Builder struct {
+	// FailInBrowser makes an invalid statement emit "<\/script>" and the error instead of
+	// keeping the error, so that the script fails with a syntax error in the browser,
+	// as the deprecated LetString and the other string emitters do.
+	// The statement is escaped so that it can be used in a Script.
+	FailInBrowser bool
+
+	stmts []string
+	err   error
+}
+
+// add adds the statement or keeps the error.
+func (b *Builder) add(stmt string, err error) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if err != nil {
+		if !b.FailInBrowser {
+			b.err = err
+			return b
+		}
+		stmt = `<\/script> add By js.Builder: ` + html.EscapeString(err.Error()) // cause ECMAscript syntax error
+	}
+	b.stmts = append(b.stmts, stmt)
+	return b
+}
+
+// Let adds a let statement with the given name and the literal of the value.
+func (b *Builder) Let(name string, val any) *Builder {
+	return b.add(Let(name, val))
+}
+
+// Const adds a const statement with the given name and the literal of the value.
+func (b *Builder) Const(name string, val any) *Builder {
+	return b.add(Const(name, val))
+}
+
+// Assign adds an assignment statement with the given name and the literal of the value.
+func (b *Builder) Assign(name string, val any) *Builder {
+	return b.add(Assign(name, val))
+}
+
+// Raw adds the statement as is.
+func (b *Builder) Raw(stmt string) *Builder {
+	return b.add(stmt, nil)
+}
+
+// Err returns the first error.
+func (b *Builder) Err() error {
+	return b.err
+}
+
+// Statements returns the statements added.
+func (b *Builder) Statements() []string {
+	return b.stmts
+}
+
+// String returns the statements one per line.
+func (b *Builder) String() string {
+	return strings.Join(b.stmts, "\n")
+}
@@ -0,0 +1,92 @@
+package js
+
+import (
+	"testing"
+)
+
+func TestIsReservedWord(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"let", true},
+		{"class", true},
+		{"await", true},
+		{"yield", true},
+		{"this", true},
+		{"myVar", false},
+		{"Let", false},
+		{"undefined", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := IsReservedWord(tt.in); got != tt.want {
+				t.Errorf("IsReservedWord(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReservedWordNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		got     func() (string, error)
+		want    string
+		wantErr string
+	}{
+		{"let reserved", func() (string, error) { return Let("class", 1) }, "", "reserved word: class"},
+		{"const reserved", func() (string, error) { return Const("await", 1) }, "", "reserved word: await"},
+		{"assign reserved", func() (string, error) { return Assign("let", 1) }, "", "reserved word: let"},
+		{"assign reserved property", func() (string, error) { return Assign("a.class", 1) }, "a.class = 1;", ""},
+		{"assign this", func() (string, error) { return Assign("this", 1) }, "", "reserved word: this"},
+		{"assign this property", func() (string, error) { return AssignJson("this.x", 1) }, "this.x = 1;", ""},
+		{"old emitters keep reserved words", func() (string, error) { return LetString("let", "x"), nil }, `let let = "x";`, ""},
+		{"old json emitters keep reserved words", func() (string, error) { return ConstJson("await", 1) }, "const await = 1;", ""},
+		{"old assignment keeps this", func() (string, error) { return AssignJson("this", 1) }, "this = 1;", ""},
+		{"sabotage invalid", func() (string, error) { return LetString("a b", "x"), nil }, `let </script> add By js.LetString: a b = "x";`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.got()
+			if (err != nil) != (tt.wantErr != "") || (err != nil && err.Error() != tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	var b Builder
+	b.Const("url", "/events").Let("count", 3).Assign("window.ready", true).Raw("start();")
+	if err := b.Err(); err != nil {
+		t.Fatalf("Err() = %v, want nil", err)
+	}
+	want := "const url = \"/events\";\nlet count = 3;\nwindow.ready = true;\nstart();"
+	if got := b.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	b = Builder{}
+	b.Let("a", 1).Let("class", 2).Let("1b", 3).Let("c", 4)
+	if err := b.Err(); err == nil || err.Error() != "reserved word: class" {
+		t.Errorf("Err() = %v, want %q", err, "reserved word: class")
+	}
+	if got := len(b.Statements()); got != 1 {
+		t.Errorf("len(Statements()) = %d, want %d", got, 1)
+	}
+
+	b = Builder{FailInBrowser: true}
+	b.Let("a<b", 1).Let("c", 2)
+	if err := b.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+	want = "<\\/script> add By js.Builder: invalid identifire: a&lt;b\nlet c = 2;"
+	if got := b.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
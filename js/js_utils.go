@@ -9,6 +9,11 @@ import (
 	"unicode"
 )
 
+// makeDeclaration emits a statement which causes a syntax error in the browser for an invalid name.
+// The string emitters such as LetString keep this behavior because they can not return an error
+// without breaking their callers, so they are deprecated in favor of Let, Const and Builder,
+// which fail with an error by default and fail in the browser only with Builder.FailInBrowser.
+// Reserved words are only rejected by those new entry points, so the output of the emitters is unchanged.
 func makeDeclaration(decl, funcName, name string, val any, format string) string {
 	if !IsIdentifire(name) {
 		name = "</script> add By " + funcName + ": " + html.EscapeString(name) // cause ECMAscript syntax error
@@ -17,20 +22,28 @@ func makeDeclaration(decl, funcName, name string, val any, format string) string
 }
 
 // LetString creates a JavaScript let statement with the given name and string value.
+//
+// Deprecated: Use Let, which returns an error for an invalid name.
 func LetString(name, val string) string {
 	return makeDeclaration("let", "js.LetString", name, template.JSEscapeString(val), `"%s"`)
 }
 
 // LetInt creates a JavaScript let statement with the given name and int value.
+//
+// Deprecated: Use Let, which returns an error for an invalid name.
 func LetInt(name string, val int) string {
 	return makeDeclaration("let", "js.LetInt", name, val, `%d`)
 }
 
 // LetBool creates a JavaScript let statement with the given name and bool value.
+//
+// Deprecated: Use Let, which returns an error for an invalid name.
 func LetBool(name string, val bool) string {
 	return makeDeclaration("let", "js.LetBool", name, val, `%t`)
 }
 
+// makeJsonDeclaration only checks that the name is an identifier, as it always did.
+// Let and Const also reject reserved words.
 func makeJsonDeclaration(decl, name string, val any) (string, error) {
 	if !IsIdentifire(name) {
 		return "", fmt.Errorf("invalid identifire: %s", name)
@@ -49,16 +62,22 @@ func LetJson(name string, val any) (string, error) {
 }
 
 // ConstString creates a JavaScript const statement with the given name and string value.
+//
+// Deprecated: Use Const, which returns an error for an invalid name.
 func ConstString(name, val string) string {
 	return makeDeclaration("const", "js.ConstString", name, template.JSEscapeString(val), `"%s"`)
 }
 
 // ConstInt creates a JavaScript const statement with the given name and int value.
+//
+// Deprecated: Use Const, which returns an error for an invalid name.
 func ConstInt(name string, val int) string {
 	return makeDeclaration("const", "js.ConstInt", name, val, `%d`)
 }
 
 // ConstBool creates a JavaScript const statement with the given name and bool value.
+//
+// Deprecated: Use Const, which returns an error for an invalid name.
 func ConstBool(name string, val bool) string {
 	return makeDeclaration("const", "js.ConstBool", name, val, `%t`)
 }
@@ -111,6 +130,55 @@ func IsIDContinue(c rune) bool {
 			!unicode.Is(unicode.Pattern_White_Space, c))
 }
 
+// reservedWords are the reserved words of ECMAScript, including those of strict mode
+// and await, which is reserved in modules.
+var reservedWords = map[string]bool{
+	"await": true, "break": true, "case": true, "catch": true, "class": true, "const": true,
+	"continue": true, "debugger": true, "default": true, "delete": true, "do": true, "else": true,
+	"enum": true, "export": true, "extends": true, "false": true, "finally": true, "for": true,
+	"function": true, "if": true, "implements": true, "import": true, "in": true,
+	"instanceof": true, "interface": true, "let": true, "new": true, "null": true,
+	"package": true, "private": true, "protected": true, "public": true, "return": true,
+	"static": true, "super": true, "switch": true, "this": true, "throw": true, "true": true,
+	"try": true, "typeof": true, "var": true, "void": true, "while": true, "with": true,
+	"yield": true,
+}
+
+// IsReservedWord reports whether the name is a reserved word of ECMAScript,
+// which can not be used as a variable name.
+func IsReservedWord(name string) bool {
+	return reservedWords[name]
+}
+
+// checkBindingName returns an error if the name can not be declared as a variable.
+func checkBindingName(name string) error {
+	if !IsIdentifire(name) {
+		return fmt.Errorf("invalid identifire: %s", name)
+	}
+	if IsReservedWord(name) {
+		return fmt.Errorf("reserved word: %s", name)
+	}
+	return nil
+}
+
+// checkPropertyAccess returns an error if the name is not a variable or its property
+// such as "a.b.c". Property names may be reserved words, as in "a.class",
+// and "this" may be used only with a property, as in "this.x".
+func checkPropertyAccess(name string) error {
+	ids := strings.Split(name, ".")
+	for i, id := range ids {
+		if !IsIdentifire(id) {
+			return fmt.Errorf("invalid identifire: %s", name)
+		}
+		if i == 0 && IsReservedWord(id) && (id != "this" || len(ids) == 1) {
+			return fmt.Errorf("reserved word: %s", id)
+		}
+	}
+	return nil
+}
+
+// isPropertyAccess reports whether the name is an identifier or its property,
+// allowing reserved words as the old emitters did.
 func isPropertyAccess(name string) bool {
 	for _, id := range strings.Split(name, ".") {
 		if !IsIdentifire(id) {
@@ -120,6 +188,8 @@ func isPropertyAccess(name string) bool {
 	return true
 }
 
+// makeAssignment emits a statement which causes a syntax error in the browser for an invalid name,
+// as makeDeclaration does.
 func makeAssignment(funcName, name string, val any, format string) string {
 	if !isPropertyAccess(name) {
 		name = "</script> add By " + funcName + ": " + html.EscapeString(name) // cause ECMAscript syntax error
@@ -128,20 +198,28 @@ func makeAssignment(funcName, name string, val any, format string) string {
 }
 
 // AssignString creates a JavaScript assignment statement with the given name and string value.
+//
+// Deprecated: Use Assign, which returns an error for an invalid name.
 func AssignString(name, val string) string {
 	return makeAssignment("js.AssignString", name, template.JSEscapeString(val), `"%s"`)
 }
 
 // AssignInt creates a JavaScript assignment statement with the given name and int value.
+//
+// Deprecated: Use Assign, which returns an error for an invalid name.
 func AssignInt(name string, val int) string {
 	return makeAssignment("js.AssignInt", name, val, `%d`)
 }
 
 // AssignBool creates a JavaScript assignment statement with the given name and bool value.
+//
+// Deprecated: Use Assign, which returns an error for an invalid name.
 func AssignBool(name string, val bool) string {
 	return makeAssignment("js.AssignBool", name, val, `%t`)
 }
 
+// makeJsonAssignment only checks the name by isPropertyAccess, as it always did.
+// Assign also rejects reserved words.
 func makeJsonAssignment(name string, val any) (string, error) {
 	if !isPropertyAccess(name) {
 		return "", fmt.Errorf("invalid identifire: %s", name)
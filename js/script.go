@@ -0,0 +1,82 @@
+package js
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/turutcrane/haat"
+	"golang.org/x/net/html/atom"
+)
+
+// Script builds a <script> element from the statements added by the embedded Builder.
+type Script struct {
+	Builder
+	// Module makes the script a module with type="module".
+	Module bool
+	// Nonce is the value of the nonce attribute, which should be a new value for each request
+	// and match the nonce of the Content-Security-Policy header.
+	Nonce string
+	// IIFE wraps the statements in an immediately invoked function
+	// so that the declarations do not become globals.
+	IIFE bool
+	// Strict puts the "use strict" directive before the statements.
+	Strict bool
+}
+
+// checkStatement returns an error if the statement would end the script element
+// or make the HTML parser treat "</script>" differently.
+func checkStatement(stmt string) error {
+	lower := strings.ToLower(stmt)
+	if strings.Contains(lower, "</script") {
+		return fmt.Errorf("statement contains </script: %s", stmt)
+	}
+	if strings.Contains(lower, "<!--") {
+		return fmt.Errorf("statement contains <!--: %s", stmt)
+	}
+	return nil
+}
+
+// Source returns the source of the script with the wrapper.
+// It returns the first error of the builder, or an error if a statement
+// contains "</script" or "<!--".
+func (s *Script) Source() (string, error) {
+	if err := s.Err(); err != nil {
+		return "", err
+	}
+	var lines []string
+	if s.Strict {
+		lines = append(lines, `"use strict";`)
+	}
+	for _, stmt := range s.Statements() {
+		if err := checkStatement(stmt); err != nil {
+			return "", err
+		}
+		lines = append(lines, stmt)
+	}
+	src := strings.Join(lines, "\n")
+	if s.IIFE {
+		src = "(() => {\n" + src + "\n})();"
+	}
+	return src, nil
+}
+
+// Element returns the <script> element of the statements.
+// See Source for the errors. It also returns an error if the nonce is not valid for haat.IsValidNonce.
+func (s *Script) Element() (*haat.Element, error) {
+	src, err := s.Source()
+	if err != nil {
+		return nil, err
+	}
+	if s.Nonce != "" && !haat.IsValidNonce(s.Nonce) {
+		return nil, fmt.Errorf("invalid nonce: %q", s.Nonce)
+	}
+	e := haat.E(atom.Script)
+	var attrs []haat.Attribute
+	if s.Module {
+		attrs = append(attrs, haat.A("type", "module"))
+	}
+	if s.Nonce != "" {
+		attrs = append(attrs, haat.A("nonce", s.Nonce))
+	}
+	return e.SetA(attrs...).C(haat.T(src)), nil
+}
@@ -0,0 +1,75 @@
+package js
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  func() *Script
+		want    string
+		wantErr string
+	}{
+		{"plain", func() *Script {
+			s := &Script{}
+			s.Const("msg", "</script><!--").Raw("console.log(msg);")
+			return s
+		}, `<script>const msg = "\u003C/script\u003E\u003C!--";` + "\n" + `console.log(msg);</script>`, ""},
+		{"module with nonce", func() *Script {
+			s := &Script{Module: true, Nonce: "abc123"}
+			s.Raw(`import "/app.js";`)
+			return s
+		}, `<script nonce="abc123" type="module">import "/app.js";</script>`, ""},
+		{"IIFE and strict", func() *Script {
+			s := &Script{IIFE: true, Strict: true}
+			s.Let("x", 1)
+			return s
+		}, "<script>(() => {\n\"use strict\";\nlet x = 1;\n})();</script>", ""},
+		{"builder error", func() *Script {
+			s := &Script{}
+			s.Let("let", 1)
+			return s
+		}, "", "reserved word: let"},
+		{"raw </script>", func() *Script {
+			s := &Script{}
+			s.Raw(`document.write("</SCRIPT>");`)
+			return s
+		}, "", `statement contains </script: document.write("</SCRIPT>");`},
+		{"fail in browser", func() *Script {
+			s := &Script{Builder: Builder{FailInBrowser: true}}
+			s.Let("let", 1).Let("x", 2)
+			return s
+		}, "<script><\\/script> add By js.Builder: reserved word: let\nlet x = 2;</script>", ""},
+		{"invalid nonce", func() *Script {
+			s := &Script{Nonce: `a" onload="x`}
+			s.Raw("x();")
+			return s
+		}, "", `invalid nonce: "a\" onload=\"x"`},
+		{"raw <!--", func() *Script {
+			s := &Script{}
+			s.Raw(`x = "<!--";`)
+			return s
+		}, "", `statement contains <!--: x = "<!--";`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := tt.script().Element()
+			if (err != nil) != (tt.wantErr != "") || (err != nil && err.Error() != tt.wantErr) {
+				t.Fatalf("Element() error = %v, want %q", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var buf strings.Builder
+			if err := e.Render(&buf); err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Element() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package js
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math"
+	"math/big"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSafeInteger is Number.MAX_SAFE_INTEGER of JavaScript.
+const maxSafeInteger = 1<<53 - 1
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	bigIntType        = reflect.TypeFor[big.Int]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+// Literal returns the JavaScript literal of the value:
+//   - nil, including nil pointers, slices and maps, becomes null
+//   - time.Time becomes new Date("...") with millisecond precision
+//   - json.Marshaler values and structs become their JSON
+//   - strings are escaped as template.JSEscapeString does
+//   - floats become numbers, or NaN, Infinity and -Infinity
+//   - integers beyond Number.MAX_SAFE_INTEGER and big.Int values become BigInt literals such as 9007199254740993n
+//   - slices and arrays become array literals and maps become object literals with sorted keys
+//
+// JavaScript throws a TypeError on arithmetic mixing Number and BigInt,
+// so all the elements of an integer slice or array become BigInt literals if one of them does,
+// and an error is returned for other arrays mixing Number and BigInt elements.
+//
+// The literal is safe to put in a <script> element.
+func Literal(val any) (string, error) {
+	var b strings.Builder
+	if err := writeLiteral(&b, reflect.ValueOf(val)); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func writeLiteral(b *strings.Builder, v reflect.Value) error {
+	if !v.IsValid() {
+		b.WriteString("null")
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			b.WriteString("null")
+			return nil
+		}
+	}
+
+	// time.Time and big.Int implement json.Marshaler, so they are checked first.
+	if v.Kind() == reflect.Pointer && (v.Type().Elem() == timeType || v.Type().Elem() == bigIntType) {
+		v = v.Elem()
+	}
+	if v.Type() == bigIntType {
+		i := v.Interface().(big.Int)
+		b.WriteString(i.String() + "n")
+		return nil
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		b.WriteString(`new Date("` + template.JSEscapeString(t.Format("2006-01-02T15:04:05.000Z07:00")) + `")`)
+		return nil
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		return writeJSON(b, v)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return writeLiteral(b, v.Elem())
+	case reflect.String:
+		b.WriteString(`"` + template.JSEscapeString(v.String()) + `"`)
+	case reflect.Bool:
+		b.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := v.Int()
+		b.WriteString(strconv.FormatInt(i, 10))
+		if i > maxSafeInteger || i < -maxSafeInteger {
+			b.WriteString("n")
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		b.WriteString(strconv.FormatUint(u, 10))
+		if u > maxSafeInteger {
+			b.WriteString("n")
+		}
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		switch {
+		case math.IsNaN(f):
+			b.WriteString("NaN")
+		case math.IsInf(f, 1):
+			b.WriteString("Infinity")
+		case math.IsInf(f, -1):
+			b.WriteString("-Infinity")
+		default:
+			b.WriteString(strconv.FormatFloat(f, 'g', -1, v.Type().Bits()))
+		}
+	case reflect.Slice, reflect.Array:
+		return writeArray(b, v)
+	case reflect.Map:
+		return writeObject(b, v)
+	case reflect.Struct:
+		return writeJSON(b, v)
+	default:
+		return fmt.Errorf("unsupported type: %s", v.Type())
+	}
+	return nil
+}
+
+// numberKind is the kind of the number a literal is.
+type numberKind int
+
+const (
+	notNumber numberKind = iota
+	numberLiteral
+	bigIntLiteral
+)
+
+// literalNumberKind returns whether the literal is a Number, a BigInt or something else.
+func literalNumberKind(lit string) numberKind {
+	s := strings.TrimPrefix(lit, "-")
+	switch {
+	case s == "NaN", s == "Infinity":
+		return numberLiteral
+	case s == "" || s[0] < '0' || '9' < s[0]:
+		return notNumber
+	case strings.HasSuffix(s, "n"):
+		return bigIntLiteral
+	}
+	return numberLiteral
+}
+
+// isSafeInteger reports whether the integer value is within Number.MAX_SAFE_INTEGER.
+func isSafeInteger(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return -maxSafeInteger <= v.Int() && v.Int() <= maxSafeInteger
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() <= maxSafeInteger
+	}
+	return true
+}
+
+// writeArray writes the slice or array as an array literal.
+func writeArray(b *strings.Builder, v reflect.Value) error {
+	bigInts := false
+	switch v.Type().Elem().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		for i := range v.Len() {
+			if !isSafeInteger(v.Index(i)) {
+				bigInts = true
+				break
+			}
+		}
+	}
+
+	kinds := map[numberKind]bool{}
+	b.WriteString("[")
+	for i := range v.Len() {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		var elem strings.Builder
+		if err := writeLiteral(&elem, v.Index(i)); err != nil {
+			return err
+		}
+		lit := elem.String()
+		if bigInts && !strings.HasSuffix(lit, "n") {
+			lit += "n"
+		}
+		kinds[literalNumberKind(lit)] = true
+		b.WriteString(lit)
+	}
+	b.WriteString("]")
+	if kinds[numberLiteral] && kinds[bigIntLiteral] {
+		return fmt.Errorf("array mixes Number and BigInt elements: %s", v.Type())
+	}
+	return nil
+}
+
+// writeObject writes the map as an object literal with the keys sorted.
+func writeObject(b *strings.Builder, v reflect.Value) error {
+	type entry struct {
+		key string
+		val reflect.Value
+	}
+	var entries []entry
+	for iter := v.MapRange(); iter.Next(); {
+		k := iter.Key()
+		var key string
+		switch k.Kind() {
+		case reflect.String:
+			key = k.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			key = strconv.FormatInt(k.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			key = strconv.FormatUint(k.Uint(), 10)
+		default:
+			return fmt.Errorf("unsupported map key type: %s", k.Type())
+		}
+		entries = append(entries, entry{key, iter.Value()})
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return strings.Compare(a.key, b.key) })
+
+	b.WriteString("{")
+	for i, e := range entries {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(`"` + template.JSEscapeString(e.key) + `":`)
+		if err := writeLiteral(b, e.val); err != nil {
+			return err
+		}
+	}
+	b.WriteString("}")
+	return nil
+}
+
+// writeJSON writes the JSON of the value, which escapes <, > and & for <script> elements.
+func writeJSON(b *strings.Builder, v reflect.Value) error {
+	jsonBytes, err := json.Marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	b.Write(jsonBytes)
+	return nil
+}
+
+// typedLiteral returns the literal of the value dispatching on its static type T,
+// so that a nil value of an interface type T becomes null as well.
+func typedLiteral[T any](val T) (string, error) {
+	var b strings.Builder
+	if err := writeLiteral(&b, reflect.ValueOf(&val).Elem()); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func makeLiteralDeclaration[T any](decl, name string, val T) (string, error) {
+	if err := checkBindingName(name); err != nil {
+		return "", err
+	}
+	lit, err := typedLiteral(val)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s = %s;", decl, name, lit), nil
+}
+
+// Let creates a JavaScript let statement with the given name and the literal of the value.
+// See Literal for the supported types. The type argument may be given to check the type
+// of the value at compile time, as in Let[float64]("ratio", r).
+func Let[T any](name string, val T) (string, error) {
+	return makeLiteralDeclaration("let", name, val)
+}
+
+// Const creates a JavaScript const statement with the given name and the literal of the value.
+// See Literal for the supported types.
+func Const[T any](name string, val T) (string, error) {
+	return makeLiteralDeclaration("const", name, val)
+}
+
+// Assign creates a JavaScript assignment statement with the given name and the literal of the value.
+// See Literal for the supported types.
+func Assign[T any](name string, val T) (string, error) {
+	if err := checkPropertyAccess(name); err != nil {
+		return "", err
+	}
+	lit, err := typedLiteral(val)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s = %s;", name, lit), nil
+}
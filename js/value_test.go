@@ -0,0 +1,119 @@
+package js
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+type rawJSON string
+
+func (r rawJSON) MarshalJSON() ([]byte, error) {
+	return []byte(r), nil
+}
+
+func TestLiteral(t *testing.T) {
+	type myStruct struct {
+		Foo string `json:"foo"`
+	}
+	var nilPtr *myStruct
+	var nilSlice []int
+	jst := time.FixedZone("JST", 9*60*60)
+
+	tests := []struct {
+		name    string
+		val     any
+		want    string
+		wantErr bool
+		errText string
+	}{
+		{"nil", nil, `null`, false, ""},
+		{"nil pointer", nilPtr, `null`, false, ""},
+		{"nil slice", nilSlice, `null`, false, ""},
+		{"string", `</script>"`, `"\u003C/script\u003E\""`, false, ""},
+		{"bool", true, `true`, false, ""},
+		{"int", -42, `-42`, false, ""},
+		{"max safe integer", int64(1<<53 - 1), `9007199254740991`, false, ""},
+		{"int64 beyond 2^53", int64(1<<53 + 1), `9007199254740993n`, false, ""},
+		{"negative int64 beyond 2^53", int64(-(1<<53 + 1)), `-9007199254740993n`, false, ""},
+		{"uint64 beyond 2^53", uint64(math.MaxUint64), `18446744073709551615n`, false, ""},
+		{"float", 1.5, `1.5`, false, ""},
+		{"float32", float32(0.1), `0.1`, false, ""},
+		{"large float", 1e21, `1e+21`, false, ""},
+		{"NaN", math.NaN(), `NaN`, false, ""},
+		{"Infinity", math.Inf(1), `Infinity`, false, ""},
+		{"-Infinity", math.Inf(-1), `-Infinity`, false, ""},
+		{"time", time.Date(2024, 1, 2, 3, 4, 5, 6000000, jst), `new Date("2024-01-02T03:04:05.006+09:00")`, false, ""},
+		{"time UTC", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), `new Date("2024-01-02T03:04:05.000Z")`, false, ""},
+		{"pointer to time", &time.Time{}, `new Date("0001-01-01T00:00:00.000Z")`, false, ""},
+		{"slice", []any{1, "a", nil, math.NaN()}, `[1,"a",null,NaN]`, false, ""},
+		{"array", [2]bool{true, false}, `[true,false]`, false, ""},
+		{"map", map[string]any{"b": 1, "a": []string{"x"}, "</script>": 2}, `{"\u003C/script\u003E":2,"a":["x"],"b":1}`, false, ""},
+		{"map with int keys", map[int]string{2: "b", 1: "a"}, `{"1":"a","2":"b"}`, false, ""},
+		{"struct", myStruct{Foo: "</script>"}, `{"foo":"\u003c/script\u003e"}`, false, ""},
+		{"big.Int", big.NewInt(2), `2n`, false, ""},
+		{"big.Int value", *big.NewInt(-3), `-3n`, false, ""},
+		{"int64 slice beyond 2^53", []int64{1, 1<<53 + 1}, `[1n,9007199254740993n]`, false, ""},
+		{"array of big.Int", []*big.Int{big.NewInt(1), nil}, `[1n,null]`, false, ""},
+		{"mixed Number and BigInt", []any{1, big.NewInt(2)}, "", true, "array mixes Number and BigInt elements"},
+		{"mixed Number and large int64", []any{1.5, int64(1 << 60)}, "", true, "array mixes Number and BigInt elements"},
+		{"json.Marshaler", rawJSON(`{"x":1}`), `{"x":1}`, false, ""},
+		{"json.RawMessage", json.RawMessage(`[1,2]`), `[1,2]`, false, ""},
+		{"unsupported type", make(chan int), "", true, "unsupported type: chan int"},
+		{"unsupported map key", map[bool]int{true: 1}, "", true, "unsupported map key type: bool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Literal(tt.val)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Literal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.errText) {
+					t.Errorf("Literal() error = %q, want to contain %q", err.Error(), tt.errText)
+				}
+			}
+			if got != tt.want {
+				t.Errorf("Literal() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLetConstAssign(t *testing.T) {
+	tests := []struct {
+		name    string
+		got     func() (string, error)
+		want    string
+		wantErr bool
+	}{
+		{"let float", func() (string, error) { return Let("x", 0.5) }, `let x = 0.5;`, false},
+		{"let nil", func() (string, error) { return Let[any]("x", nil) }, `let x = null;`, false},
+		{"let typed float", func() (string, error) { return Let[float64]("x", 1) }, `let x = 1;`, false},
+		{"let typed nil slice", func() (string, error) { return Let[[]int64]("x", nil) }, `let x = null;`, false},
+		{"let nil interface", func() (string, error) { return Let[fmt.Stringer]("x", nil) }, `let x = null;`, false},
+		{"const big int", func() (string, error) { return Const("id", int64(1<<60)) }, `const id = 1152921504606846976n;`, false},
+		{"const map", func() (string, error) { return Const("m", map[string]int{"b": 2, "a": 1}) }, `const m = {"a":1,"b":2};`, false},
+		{"assign slice", func() (string, error) { return Assign("a.b", []string{"x"}) }, `a.b = ["x"];`, false},
+		{"let invalid identifier", func() (string, error) { return Let("1x", 1) }, ``, true},
+		{"assign invalid property", func() (string, error) { return Assign("a.1b", 1) }, ``, true},
+		{"const unsupported type", func() (string, error) { return Const("f", func() {}) }, ``, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.got()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
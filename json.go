@@ -0,0 +1,172 @@
+package haat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// marshalNode returns the JSON form of the whole tree of the node.
+func marshalNode(n *html.Node) ([]byte, error) {
+	return json.Marshal(newJSONNode(n, 0, 0, -1))
+}
+
+// nodeType returns the node type of the type name in the JSON form.
+func nodeType(name string) (html.NodeType, bool) {
+	for t, s := range jsonNodeTypes {
+		if s == name && t != html.ErrorNode {
+			return t, true
+		}
+	}
+	return html.ErrorNode, false
+}
+
+// build returns the node of the JSON form and its descendants.
+// The atom of an element is looked up from its tag name.
+func (j *jsonNode) build() (*html.Node, error) {
+	if j == nil {
+		return nil, fmt.Errorf("haat: null node")
+	}
+	t, ok := nodeType(j.Type)
+	if !ok {
+		return nil, fmt.Errorf("haat: unknown node type: %q", j.Type)
+	}
+	if j.More > 0 {
+		return nil, fmt.Errorf("haat: %d children of %s %q are omitted", j.More, j.Type, j.Data)
+	}
+	if j.Truncated {
+		return nil, fmt.Errorf("haat: data of %s %q is truncated", j.Type, j.Data)
+	}
+	n := &html.Node{
+		Type:      t,
+		Data:      j.Data,
+		Namespace: j.Namespace,
+	}
+	if t == html.ElementNode {
+		n.DataAtom = atom.Lookup([]byte(j.Data))
+	}
+	for _, a := range j.Attr {
+		n.Attr = append(n.Attr, html.Attribute{Namespace: a.Namespace, Key: a.Key, Val: a.Val})
+	}
+	for _, c := range j.Children {
+		cn, err := c.build()
+		if err != nil {
+			return nil, err
+		}
+		n.AppendChild(cn)
+	}
+	return n, nil
+}
+
+// unmarshalNode sets the node decoded from the JSON data to the receiver n of the node type t.
+// The children of n are replaced, and n keeps its position in the tree.
+func unmarshalNode(data []byte, n *html.Node, t html.NodeType) error {
+	var j jsonNode
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	m, err := j.build()
+	if err != nil {
+		return err
+	}
+	if m.Type != t {
+		return fmt.Errorf("haat: can not unmarshal %s node into %s", j.Type, jsonNodeTypes[t])
+	}
+	for c := n.FirstChild; c != nil; c = n.FirstChild {
+		n.RemoveChild(c)
+	}
+	n.Type = m.Type
+	n.DataAtom = m.DataAtom
+	n.Data = m.Data
+	n.Namespace = m.Namespace
+	n.Attr = m.Attr
+	for c := m.FirstChild; c != nil; c = m.FirstChild {
+		m.RemoveChild(c)
+		n.AppendChild(c)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. The whole tree is encoded.
+func (d *Document) MarshalJSON() ([]byte, error) {
+	return marshalNode((*html.Node)(d))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Document) UnmarshalJSON(data []byte) error {
+	return unmarshalNode(data, (*html.Node)(d), html.DocumentNode)
+}
+
+// MarshalJSON implements json.Marshaler. The element and its descendants are encoded.
+func (e *Element) MarshalJSON() ([]byte, error) {
+	return marshalNode((*html.Node)(e))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (e *Element) UnmarshalJSON(data []byte) error {
+	return unmarshalNode(data, (*html.Node)(e), html.ElementNode)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t *Text) MarshalJSON() ([]byte, error) {
+	return marshalNode((*html.Node)(t))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Text) UnmarshalJSON(data []byte) error {
+	return unmarshalNode(data, (*html.Node)(t), html.TextNode)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t *RawText) MarshalJSON() ([]byte, error) {
+	return marshalNode((*html.Node)(t))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *RawText) UnmarshalJSON(data []byte) error {
+	return unmarshalNode(data, (*html.Node)(t), html.RawNode)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d *Doctype) MarshalJSON() ([]byte, error) {
+	return marshalNode((*html.Node)(d))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Doctype) UnmarshalJSON(data []byte) error {
+	return unmarshalNode(data, (*html.Node)(d), html.DoctypeNode)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c *Comment) MarshalJSON() ([]byte, error) {
+	return marshalNode((*html.Node)(c))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Comment) UnmarshalJSON(data []byte) error {
+	return unmarshalNode(data, (*html.Node)(c), html.CommentNode)
+}
+
+// EncodeTree writes the JSON form of the node and its descendants.
+// Namespaces and attributes are preserved, so the decoded tree renders the same as the node.
+func EncodeTree(w io.Writer, n Node) error {
+	return json.NewEncoder(w).Encode(newJSONNode(convertNode(n), 0, 0, -1))
+}
+
+// DecodeTree reads the JSON form written by EncodeTree and returns the node
+// as *Document, *Element, *Text, *RawText, *Doctype or *Comment.
+// The JSON written by Dump with truncated text or omitted children is rejected.
+func DecodeTree(r io.Reader) (Node, error) {
+	var j jsonNode
+	if err := json.NewDecoder(r).Decode(&j); err != nil {
+		return nil, err
+	}
+	n, err := j.build()
+	if err != nil {
+		return nil, err
+	}
+	return wrapNode(n), nil
+}
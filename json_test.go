@@ -0,0 +1,122 @@
+package haat
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const jsonSource = `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd">
+<html lang="en"><head><title>a &lt; b</title><style>p > a { color: red }</style></head>
+<body>
+<!-- comment -->
+<p id="x" class="b a" data-v='{"k":1}'>Hello <b>world</b></p>
+<svg viewBox="0 0 10 10"><use xlink:href="#icon"></use><foreignObject><p>in svg</p></foreignObject></svg>
+<math><mi>x</mi></math>
+<pre>
+
+text</pre>
+<script>if (a < b) {}</script>
+</body></html>`
+
+func TestJSONRoundTrip(t *testing.T) {
+	doc, err := ParseHTML(strings.NewReader(jsonSource))
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	doc.Query("p")[0].AppendC(RawT("<i>raw</i>"))
+	var expected bytes.Buffer
+	if err := doc.Render(&expected); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	var doc2 Document
+	if err := json.Unmarshal(data, &doc2); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	var actual bytes.Buffer
+	if err := doc2.Render(&actual); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	if actual.String() != expected.String() {
+		t.Errorf("got: %v\nwant: %v", actual.String(), expected.String())
+	}
+	if use := doc2.Query("[href]")[0]; use.Namespace != NamespaceSVG || use.Attr[0].Namespace != "xlink" {
+		t.Errorf("got: %+v\nwant: svg use with xlink:href", use)
+	}
+	if actual, expected := doc2.Query("p")[0].DataAtom, atom.P; actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+	for c := (*html.Node)(&doc2).FirstChild; c != nil; c = c.NextSibling {
+		if c.Parent != (*html.Node)(&doc2) {
+			t.Errorf("got: %v\nwant: children parented to the receiver", c.Parent)
+		}
+	}
+}
+
+func TestEncodeDecodeTree(t *testing.T) {
+	svg := NewElementNS(NamespaceSVG, "svg").C(
+		NewElementNS(NamespaceSVG, "use").SetA(AttrXlinkHref("#icon")),
+	)
+	var buf bytes.Buffer
+	if err := EncodeTree(&buf, svg); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	expected := `{"type":"element","namespace":"svg","data":"svg","children":[{"type":"element","namespace":"svg","data":"use","attr":[{"namespace":"xlink","key":"href","val":"#icon"}]}]}` + "\n"
+	if actual := buf.String(); actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+
+	n, err := DecodeTree(&buf)
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	e, ok := n.(*Element)
+	if !ok {
+		t.Fatalf("got: %T\nwant: *Element", n)
+	}
+	actual, _ := e.OuterHTML()
+	if expected, _ := svg.OuterHTML(); actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+
+	n, err = DecodeTree(strings.NewReader(`{"type":"comment","data":" c "}`))
+	if c, ok := n.(*Comment); err != nil || !ok || c.Data != " c " {
+		t.Errorf("got: %v %v\nwant: comment", n, err)
+	}
+
+	buf.Reset()
+	if err := Dump(&buf, T("abc"), DumpOptions{JSON: true, MaxText: 2}); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	if _, err := DecodeTree(&buf); err == nil {
+		t.Errorf("got: %v\nwant: error", err)
+	}
+}
+
+func TestUnmarshalJSONError(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`{"type":"text","data":"a"}`, "haat: can not unmarshal text node into element"},
+		{`{"type":"node"}`, `haat: unknown node type: "node"`},
+		{`{"type":"element","data":"p","more":2}`, `haat: 2 children of element "p" are omitted`},
+		{`{"type":"element","data":"p","children":[null]}`, "haat: null node"},
+		{`{"type":"text","data":"ab","truncated":true}`, `haat: data of text "ab" is truncated`},
+	}
+	for _, tt := range tests {
+		var e Element
+		if err := json.Unmarshal([]byte(tt.data), &e); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: got: %v\nwant: %v", tt.data, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package haat
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// blockElements are the HTML elements which are not rendered inline,
+// so that the whitespace around them is not significant.
+// Other elements, including custom elements, are assumed to be inline,
+// and an element with one of them as a child is rendered as is by RenderIndent.
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "base": true, "blockquote": true,
+	"body": true, "caption": true, "center": true, "col": true, "colgroup": true, "dd": true,
+	"details": true, "dialog": true, "dir": true, "div": true, "dl": true, "dt": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true,
+	"frame": true, "frameset": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "head": true, "header": true, "hgroup": true, "hr": true, "html": true,
+	"legend": true, "li": true, "link": true, "listing": true, "main": true, "menu": true,
+	"meta": true, "nav": true, "ol": true, "optgroup": true, "option": true, "p": true,
+	"param": true, "pre": true, "script": true, "search": true, "section": true, "source": true,
+	"style": true, "summary": true, "table": true, "tbody": true, "td": true, "template": true,
+	"tfoot": true, "th": true, "thead": true, "title": true, "tr": true, "track": true,
+	"ul": true, "xmp": true,
+}
+
+// isBlank reports whether the node is a text node of only whitespace.
+func isBlank(n *html.Node) bool {
+	return n.Type == html.TextNode && strings.TrimFunc(n.Data, isASCIIWhitespace) == ""
+}
+
+// indentable reports whether the children of the node can be put on their own lines
+// without changing the rendering: they are only block elements, comments and whitespace.
+func indentable(n *html.Node) bool {
+	if n.Type == html.ElementNode {
+		if n.Namespace != "" || childTextNodesAreLiteral(n) {
+			return false
+		}
+		switch n.Data {
+		case "pre", "listing", "textarea", "template":
+			return false
+		}
+	}
+	found := false
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case isBlank(c):
+		case c.Type == html.ElementNode && c.Namespace == "" && blockElements[c.Data],
+			c.Type == html.CommentNode, c.Type == html.DoctypeNode:
+			found = true
+		default:
+			return false
+		}
+	}
+	return found
+}
+
+type indenter struct {
+	w      *bufio.Writer
+	indent string
+}
+
+func (p *indenter) render(n *html.Node, depth int) error {
+	if !indentable(n) {
+		return html.Render(p.w, n)
+	}
+	if n.Type == html.ElementNode {
+		tag, err := startTag(n)
+		if err != nil {
+			return err
+		}
+		if _, err := p.w.WriteString(tag); err != nil {
+			return err
+		}
+	}
+	childDepth := depth
+	if n.Type == html.ElementNode {
+		childDepth++
+	}
+	first := true
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if isBlank(c) {
+			continue
+		}
+		if n.Type != html.DocumentNode || !first {
+			if _, err := p.w.WriteString("\n" + strings.Repeat(p.indent, childDepth)); err != nil {
+				return err
+			}
+		}
+		first = false
+		if err := p.render(c, childDepth); err != nil {
+			return err
+		}
+	}
+	if n.Type == html.ElementNode {
+		if _, err := p.w.WriteString("\n" + strings.Repeat(p.indent, depth) + "</" + n.Data + ">"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderIndent renders the node with the indentation and a newline at the end.
+func renderIndent(w io.Writer, n *html.Node, indent string) error {
+	p := &indenter{w: bufio.NewWriter(w), indent: indent}
+	if err := p.render(n, 0); err != nil {
+		return err
+	}
+	if err := p.w.WriteByte('\n'); err != nil {
+		return err
+	}
+	return p.w.Flush()
+}
+
+// RenderIndent renders the document putting each element on its own line indented by the given string.
+// Only elements whose children are block elements, comments and whitespace are indented,
+// and their whitespace-only text children are replaced; the others are rendered as is,
+// so the document renders the same in a browser.
+func (d *Document) RenderIndent(w io.Writer, indent string, checker ...Checker) error {
+	for _, html := range d.Query("html") {
+		for _, c := range checker {
+			if err := c(html); err != nil {
+				return err
+			}
+		}
+	}
+	return renderIndent(w, (*html.Node)(d), indent)
+}
+
+func (e *Element) RenderIndent(w io.Writer, indent string, checker ...Checker) error {
+	for _, c := range checker {
+		if err := c(e); err != nil {
+			return err
+		}
+	}
+	return renderIndent(w, (*html.Node)(e), indent)
+}
@@ -0,0 +1,89 @@
+package haat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html/atom"
+)
+
+func TestRenderIndent(t *testing.T) {
+	src := `<!DOCTYPE html><html><head><title>T</title><style>p { color: red }</style></head>
+<body><!-- nav --><nav><ul><li><a href="/">Home</a></li><li>About</li></ul></nav>
+<main><p>Hello <b>world</b></p><pre>  keep
+</pre><div><span>a</span> <span>b</span></div></main></body></html>`
+	doc, err := ParseHTML(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	var buf bytes.Buffer
+	if err := doc.RenderIndent(&buf, "  "); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	expected := `<!DOCTYPE html>
+<html>
+  <head>
+    <title>T</title>
+    <style>p { color: red }</style>
+  </head>
+  <body>
+    <!-- nav -->
+    <nav>
+      <ul>
+        <li><a href="/">Home</a></li>
+        <li>About</li>
+      </ul>
+    </nav>
+    <main>
+      <p>Hello <b>world</b></p>
+      <pre>  keep
+</pre>
+      <div><span>a</span> <span>b</span></div>
+    </main>
+  </body>
+</html>
+`
+	if actual := buf.String(); actual != expected {
+		t.Errorf("got:\n%v\nwant:\n%v", actual, expected)
+	}
+
+	// Formatting is stable and keeps the structure.
+	doc2, err := ParseHTML(strings.NewReader(expected))
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	if changes := Diff(doc, doc2, &DiffOptions{IgnoreWhitespace: true}); len(changes) != 0 {
+		t.Errorf("got: %v\nwant: no changes", FormatChanges(changes))
+	}
+	buf.Reset()
+	if err := doc2.RenderIndent(&buf, "  "); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	if actual := buf.String(); actual != expected {
+		t.Errorf("got:\n%v\nwant:\n%v", actual, expected)
+	}
+	if actual, expected := doc2.Query("pre")[0].TextContent(), doc.Query("pre")[0].TextContent(); actual != expected {
+		t.Errorf("got: %q\nwant: %q", actual, expected)
+	}
+}
+
+func TestRenderIndentInline(t *testing.T) {
+	for _, src := range []string{
+		`<p><my-icon>a</my-icon><my-icon>b</my-icon></p>`,
+		`<div><font>a</font><tt>b</tt><big>c</big><nobr>d</nobr><acronym>e</acronym><slot>f</slot></div>`,
+		`<div><svg></svg><svg></svg></div>`,
+	} {
+		frag, err := ParseFragmentString(src, NewElement(atom.Body))
+		if err != nil {
+			t.Fatalf("got: %v\nwant: %v", err, nil)
+		}
+		var buf bytes.Buffer
+		if err := frag[0].(*Element).RenderIndent(&buf, "  "); err != nil {
+			t.Fatalf("got: %v\nwant: %v", err, nil)
+		}
+		if actual := buf.String(); actual != src+"\n" {
+			t.Errorf("got: %v\nwant: %v", actual, src)
+		}
+	}
+}
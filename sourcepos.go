@@ -0,0 +1,518 @@
+package haat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+	"weak"
+
+	"golang.org/x/net/html"
+)
+
+// SourcePos is the position of a start tag in the parsed source.
+// Line and Col are 1-based, and Col counts runes.
+type SourcePos struct {
+	File string
+	Line int
+	Col  int
+}
+
+// IsValid returns true if the position has a line number.
+func (p SourcePos) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position in the form "file:line:col".
+func (p SourcePos) String() string {
+	switch {
+	case !p.IsValid():
+		return p.File
+	case p.File == "":
+		return fmt.Sprintf("%d:%d", p.Line, p.Col)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}
+
+// sourcePositions is the side table of source positions keyed by weak pointers to the nodes,
+// so that it does not keep the parsed nodes alive. The entry of a node is removed
+// when the node is garbage collected, so a detached node keeps its position while it is alive.
+// The entry of a document is its sourceFile.
+var sourcePositions sync.Map
+
+// sourceFile is the entry of a parsed document in the side table.
+type sourceFile struct {
+	name string
+	// aligned is false if no positions are recorded because the tokens could not be aligned with the tree.
+	aligned bool
+}
+
+// loadSourcePos returns the source position of the node from the side table.
+func loadSourcePos(n *html.Node) (SourcePos, bool) {
+	if p, ok := sourcePositions.Load(weak.Make(n)); ok {
+		p, ok := p.(SourcePos)
+		return p, ok
+	}
+	return SourcePos{}, false
+}
+
+// storeNode stores the entry of the node to the side table until the node is garbage collected.
+func storeNode(n *html.Node, v any) {
+	key := weak.Make(n)
+	if _, loaded := sourcePositions.Swap(key, v); !loaded {
+		runtime.AddCleanup(n, func(key weak.Pointer[html.Node]) {
+			sourcePositions.Delete(key)
+		}, key)
+	}
+}
+
+// storeSourcePos stores the positions of the elements created for the start tags
+// and the file name of the document to the side table.
+func storeSourcePos(t *sourceTree, filename string) {
+	storeNode(t.root, sourceFile{name: filename, aligned: t.aligned})
+	for i, n := range t.created {
+		storeNode(n, t.tokens[i].pos)
+	}
+}
+
+// SourcePos returns the position of the start tag of the element.
+// It is only available for elements parsed by ParseHTMLWithSourcePos
+// and not for elements created by the parser implicitly or by Clone.
+func (e *Element) SourcePos() (SourcePos, bool) {
+	return loadSourcePos((*html.Node)(e))
+}
+
+// SourceFile returns the file name given to ParseHTMLWithSourcePos.
+func (d *Document) SourceFile() string {
+	f, _ := sourcePositions.Load(weak.Make((*html.Node)(d)))
+	file, _ := f.(sourceFile)
+	return file.name
+}
+
+// HasSourcePos reports whether the positions of the elements are recorded by ParseHTMLWithSourcePos.
+// They are not if the tokens of the source could not be aligned with the parsed tree,
+// and then SourcePos returns no position for any element of the document.
+func (d *Document) HasSourcePos() bool {
+	f, _ := sourcePositions.Load(weak.Make((*html.Node)(d)))
+	file, _ := f.(sourceFile)
+	return file.aligned
+}
+
+// ForgetSourcePos removes the source positions of the document from the side table.
+// They are removed when the nodes are garbage collected,
+// so it is only needed to release them earlier.
+func (d *Document) ForgetSourcePos() {
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		sourcePositions.Delete(weak.Make(n))
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk((*html.Node)(d))
+}
+
+// sourceToken is a token found by the tokenizer with its position.
+type sourceToken struct {
+	typ    html.TokenType
+	name   string
+	data   string
+	pos    SourcePos
+	offset int
+	end    int
+	// foreign is true for the tags in SVG or MathML content.
+	foreign bool
+}
+
+// htmlIntegrationPoints are the foreign elements whose contents are HTML.
+var htmlIntegrationPoints = map[string]bool{
+	"foreignobject": true, "desc": true, "title": true,
+	"annotation-xml": true, "mi": true, "mo": true, "mn": true, "ms": true, "mtext": true,
+}
+
+// breakoutTags are the HTML start tags which end foreign content.
+var breakoutTags = map[string]bool{
+	"b": true, "big": true, "blockquote": true, "body": true, "br": true, "center": true,
+	"code": true, "dd": true, "div": true, "dl": true, "dt": true, "em": true, "embed": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "head": true,
+	"hr": true, "i": true, "img": true, "li": true, "listing": true, "menu": true, "meta": true,
+	"nobr": true, "ol": true, "p": true, "pre": true, "ruby": true, "s": true, "small": true,
+	"span": true, "strong": true, "strike": true, "sub": true, "sup": true, "table": true,
+	"tt": true, "u": true, "ul": true, "var": true,
+}
+
+// openTag is an element opened by a start tag, which tokenizeSource tracks
+// to know the tags in foreign content, where the parser reads no raw text.
+type openTag struct {
+	name    string
+	foreign bool
+}
+
+// inForeign reports whether the contents of the innermost open element are foreign content.
+func inForeign(open []openTag) bool {
+	if len(open) == 0 {
+		return false
+	}
+	top := open[len(open)-1]
+	return top.foreign && !htmlIntegrationPoints[top.name]
+}
+
+// tokenizeSource returns the tokens of the source with their positions.
+func tokenizeSource(src []byte, filename string) []sourceToken {
+	var lineStarts []int
+	lineStarts = append(lineStarts, 0)
+	for i, c := range src {
+		if c == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	position := func(offset int) SourcePos {
+		line := sort.Search(len(lineStarts), func(i int) bool { return lineStarts[i] > offset })
+		col := utf8.RuneCount(src[lineStarts[line-1]:offset]) + 1
+		return SourcePos{File: filename, Line: line, Col: col}
+	}
+
+	var tokens []sourceToken
+	var open []openTag
+	z := html.NewTokenizer(bytes.NewReader(src))
+	offset := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return tokens
+		}
+		t := sourceToken{typ: tt, pos: position(offset), offset: offset}
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			t.name = string(name)
+			if breakoutTags[t.name] {
+				for inForeign(open) {
+					open = open[:len(open)-1]
+				}
+			}
+			t.foreign = inForeign(open) || t.name == "svg" || t.name == "math"
+			if t.foreign {
+				// as the parser does, so that the tokens are the same as those it reads
+				z.NextIsNotRawText()
+			}
+			if tt == html.StartTagToken && (t.foreign || !voidTags[t.name]) {
+				open = append(open, openTag{name: t.name, foreign: t.foreign})
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			t.name = string(name)
+			for j := len(open) - 1; j >= 0; j-- {
+				if open[j].name == t.name {
+					t.foreign = open[j].foreign
+					open = open[:j]
+					break
+				}
+			}
+		case html.TextToken, html.DoctypeToken:
+			t.data = string(z.Text())
+		}
+		offset += len(z.Raw())
+		t.end = offset
+		tokens = append(tokens, t)
+	}
+}
+
+// rawTextTags are the HTML elements whose contents the tokenizer reads as text up to their end tag.
+// Foreign elements with the same names, such as <title> in SVG, have no raw text.
+var rawTextTags = map[string]bool{
+	"iframe": true, "noembed": true, "noframes": true, "noscript": true, "plaintext": true,
+	"script": true, "style": true, "textarea": true, "title": true, "xmp": true,
+}
+
+// markerName returns the name of the markers, which does not appear in the source,
+// so that the markers are not mistaken for the attributes and comments of the source.
+// The attribute of the name is added to each start tag with the index of its token,
+// and the comments prefixed by the name and ":" are added before and after end tags
+// and before text, which the parser inserts at its current node.
+func markerName(src []byte) string {
+	lowerSrc := bytes.ToLower(src)
+	name := "haat-source-pos"
+	for i := 0; bytes.Contains(lowerSrc, []byte(name)); i++ {
+		name = "haat-source-pos-" + strconv.Itoa(i)
+	}
+	return name
+}
+
+// markSource returns the source with the markers of the tokens.
+// Text is not marked in raw text elements nor at the start of <pre>,
+// where a marker would change how the parser reads the text.
+func markSource(src []byte, tokens []sourceToken, marker string) []byte {
+	var b bytes.Buffer
+	last := 0
+	for i, t := range tokens {
+		switch t.typ {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			end := t.offset + 1
+			for end < len(src) && !bytes.ContainsRune([]byte(" \t\n\f\r/>"), rune(src[end])) {
+				end++
+			}
+			b.Write(src[last:end])
+			fmt.Fprintf(&b, ` %s="%d"`, marker, i)
+			last = end
+		case html.EndTagToken:
+			if rawTextTags[t.name] && !t.foreign {
+				continue
+			}
+			b.Write(src[last:t.offset])
+			fmt.Fprintf(&b, "<!--%s:b%d-->", marker, i)
+			b.Write(src[t.offset:t.end])
+			fmt.Fprintf(&b, "<!--%s:a%d-->", marker, i)
+			last = t.end
+		case html.TextToken:
+			if strings.TrimFunc(t.data, isASCIIWhitespace) == "" {
+				continue
+			}
+			if i > 0 && tokens[i-1].typ == html.StartTagToken && !tokens[i-1].foreign {
+				switch prev := tokens[i-1].name; {
+				case rawTextTags[prev], prev == "pre", prev == "listing":
+					continue
+				}
+			}
+			b.Write(src[last:t.offset])
+			fmt.Fprintf(&b, "<!--%s:b%d-->", marker, i)
+			last = t.offset
+		}
+	}
+	b.Write(src[last:])
+	return b.Bytes()
+}
+
+// sourceTree is a parsed document whose nodes are aligned with the tokens of the source.
+type sourceTree struct {
+	root   *html.Node
+	tokens []sourceToken
+	// marker is the name of the markers returned by markerName.
+	marker string
+	// aligned is false if the markers changed the tree, and then nothing is aligned.
+	aligned bool
+	// created is the element the parser created for each start tag token.
+	created map[int]*html.Node
+	// origin is the start tag token of each element, including the elements
+	// the parser re-created from the start tag, such as misnested formatting elements.
+	origin map[*html.Node]int
+	// before and after are the current nodes of the parser before and after each end tag token.
+	// before is also the current node before each text token.
+	before, after map[int]*html.Node
+	// endTag is the end tag token for which the parser inserted each element.
+	endTag map[*html.Node]int
+}
+
+// collect records the markers under the node and removes them.
+func (t *sourceTree) collect(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.ElementNode:
+			for _, a := range c.Attr {
+				if a.Namespace != "" || a.Key != t.marker {
+					continue
+				}
+				if i, err := strconv.Atoi(a.Val); err == nil {
+					if _, ok := t.created[i]; !ok {
+						t.created[i] = c
+					}
+					t.origin[c] = i
+				}
+			}
+			c.Attr = slices.DeleteFunc(c.Attr, func(a html.Attribute) bool {
+				return a.Namespace == "" && a.Key == t.marker
+			})
+			t.collect(c)
+		case html.CommentNode:
+			marker, ok := strings.CutPrefix(c.Data, t.marker+":")
+			if !ok || marker == "" {
+				break
+			}
+			i, err := strconv.Atoi(marker[1:])
+			if err != nil {
+				break
+			}
+			switch marker[0] {
+			case 'b':
+				t.before[i] = n
+				if next != nil && next.Type == html.ElementNode && t.tokens[i].typ == html.EndTagToken {
+					t.endTag[next] = i
+				}
+			case 'a':
+				t.after[i] = n
+			}
+			n.RemoveChild(c)
+		}
+		c = next
+	}
+}
+
+// mergeText merges the adjacent text nodes split by the markers.
+func mergeText(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		for c.Type == html.TextNode && c.NextSibling != nil && c.NextSibling.Type == html.TextNode {
+			c.Data += c.NextSibling.Data
+			n.RemoveChild(c.NextSibling)
+		}
+		mergeText(c)
+	}
+}
+
+// sameTree reports whether the trees have the same nodes.
+func sameTree(a, b *html.Node) bool {
+	if a.Type != b.Type || a.DataAtom != b.DataAtom || a.Data != b.Data || a.Namespace != b.Namespace || !slices.Equal(a.Attr, b.Attr) {
+		return false
+	}
+	ca, cb := a.FirstChild, b.FirstChild
+	for ; ca != nil && cb != nil; ca, cb = ca.NextSibling, cb.NextSibling {
+		if !sameTree(ca, cb) {
+			return false
+		}
+	}
+	return ca == nil && cb == nil
+}
+
+// parseSource parses the source with the markers of the tokens,
+// so that the nodes are aligned with the tokens that the parser actually used for them.
+// If the markers change the tree, the source is parsed without them and nothing is aligned.
+func parseSource(src []byte, filename string) (*sourceTree, error) {
+	tokens := tokenizeSource(src, filename)
+	marker := markerName(src)
+	n, err := html.Parse(bytes.NewReader(markSource(src, tokens, marker)))
+	if err != nil {
+		return nil, err
+	}
+	t := &sourceTree{
+		root:    n,
+		tokens:  tokens,
+		marker:  marker,
+		aligned: true,
+		created: map[int]*html.Node{},
+		origin:  map[*html.Node]int{},
+		before:  map[int]*html.Node{},
+		after:   map[int]*html.Node{},
+		endTag:  map[*html.Node]int{},
+	}
+	t.collect(n)
+	mergeText(n)
+
+	plain, err := html.Parse(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	if !sameTree(n, plain) {
+		return &sourceTree{root: plain, tokens: tokens}, nil
+	}
+	return t, nil
+}
+
+// ParseHTMLWithSourcePos parses the HTML page from the given reader
+// and records the position of the start tag of each element.
+// The filename is used in the positions reported by SourcePos, Issue and QueryOne.
+// HasSourcePos reports whether the positions could be recorded.
+func ParseHTMLWithSourcePos(r io.Reader, filename string) (*Document, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	t, err := parseSource(src, filename)
+	if err != nil {
+		return nil, err
+	}
+	storeSourcePos(t, filename)
+	return (*Document)(t.root), nil
+}
+
+// Issue is an error found in an element, with the source position of the element if known.
+type Issue struct {
+	Pos     SourcePos
+	Element *Element
+	Message string
+}
+
+// NewIssue creates a new issue of the element with the formatted message.
+func NewIssue(e *Element, format string, a ...any) *Issue {
+	issue := &Issue{
+		Element: e,
+		Message: fmt.Sprintf(format, a...),
+	}
+	if e != nil {
+		issue.Pos, _ = e.SourcePos()
+	}
+	return issue
+}
+
+func (i *Issue) Error() string {
+	if i.Pos.IsValid() {
+		return i.Pos.String() + ": " + i.Message
+	}
+	return i.Message
+}
+
+// joinIssues returns nil for no issue, the issue itself for one issue,
+// and the issues joined by errors.Join for more.
+func joinIssues(issues []*Issue) error {
+	switch len(issues) {
+	case 0:
+		return nil
+	case 1:
+		return issues[0]
+	}
+	errs := make([]error, len(issues))
+	for i, issue := range issues {
+		errs[i] = issue
+	}
+	return errors.Join(errs...)
+}
+
+// Issues returns the issues in the error returned by a checker,
+// which may join several issues. Other errors in it are ignored.
+func Issues(err error) []*Issue {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var issues []*Issue
+		for _, err := range joined.Unwrap() {
+			issues = append(issues, Issues(err)...)
+		}
+		return issues
+	}
+	var issue *Issue
+	if errors.As(err, &issue) {
+		return []*Issue{issue}
+	}
+	return nil
+}
+
+// notFoundError returns the error of QueryOne with the position of the queried node.
+func notFoundError(pos string, selector string) error {
+	if pos == "" {
+		return fmt.Errorf("no element matches %q", selector)
+	}
+	return fmt.Errorf("%s: no element matches %q", pos, selector)
+}
+
+// QueryOne returns the first node that matches the selector.
+// It returns an error if no node matches.
+func (d *Document) QueryOne(selector string) (*Element, error) {
+	elements := d.Query(selector)
+	if len(elements) == 0 {
+		return nil, notFoundError(d.SourceFile(), selector)
+	}
+	return elements[0], nil
+}
+
+func (e *Element) QueryOne(selector string) (*Element, error) {
+	elements := e.Query(selector)
+	if len(elements) == 0 {
+		pos, _ := e.SourcePos()
+		return nil, notFoundError(pos.String(), selector)
+	}
+	return elements[0], nil
+}
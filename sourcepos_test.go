@@ -0,0 +1,188 @@
+package haat
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+	"weak"
+
+	"golang.org/x/net/html"
+)
+
+func TestSourcePos(t *testing.T) {
+	src := `<!DOCTYPE html>
+<html><head>
+<title>Hello</title>
+</head>
+<body>
+  <table><div id="a">x</div><tr><td>1</td></tr></table>
+  <p>Hello <span id="a">λ</span> <b>x</b></p>
+</body></html>`
+
+	doc, err := ParseHTMLWithSourcePos(strings.NewReader(src), "index.html")
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	defer doc.ForgetSourcePos()
+
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"html", "index.html:2:1"},
+		{"head", "index.html:2:7"},
+		{"title", "index.html:3:1"},
+		{"table", "index.html:6:3"},
+		{"div", "index.html:6:10"},
+		{"td", "index.html:6:33"},
+		{"span", "index.html:7:12"},
+		{"b", "index.html:7:34"},
+	}
+	for _, tt := range tests {
+		pos, ok := doc.Query(tt.selector)[0].SourcePos()
+		if !ok || pos.String() != tt.want {
+			t.Errorf("%s: got: %v\nwant: %v", tt.selector, pos, tt.want)
+		}
+	}
+	if pos, ok := doc.Query("tbody")[0].SourcePos(); ok {
+		t.Errorf("tbody: got: %v\nwant: none", pos)
+	}
+
+	err = IDDuplicateCheck(doc.Query("html")[0])
+	var issue *Issue
+	if !errors.As(err, &issue) || err.Error() != "index.html:7:12: duplicate id: a" {
+		t.Errorf("got: %v\nwant: %v", err, "index.html:7:12: duplicate id: a")
+	}
+
+	_, err = doc.QueryOne("#nothing")
+	if err == nil || err.Error() != `index.html: no element matches "#nothing"` {
+		t.Errorf("got: %v\nwant: %v", err, "not found error")
+	}
+	_, err = doc.Query("p")[0].QueryOne("i")
+	if err == nil || err.Error() != `index.html:7:3: no element matches "i"` {
+		t.Errorf("got: %v\nwant: %v", err, "not found error")
+	}
+}
+
+func TestSourcePosParserCreated(t *testing.T) {
+	doc, err := ParseHTMLWithSourcePos(strings.NewReader("<p><b>1<p>2</b>3</p>\n<div><b>later</b></div>"), "f")
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"p:nth-of-type(1)", "f:1:1"},
+		{"p:nth-of-type(1) > b", "f:1:4"},
+		{"p:nth-of-type(2)", "f:1:8"},
+		{"p:nth-of-type(2) > b", ""},
+		{"div > b", "f:2:6"},
+	}
+	for _, tt := range tests {
+		pos, ok := doc.Query(tt.selector)[0].SourcePos()
+		if ok != (tt.want != "") || (ok && pos.String() != tt.want) {
+			t.Errorf("%s: got: %v\nwant: %v", tt.selector, pos, tt.want)
+		}
+	}
+
+	// an implied <p> has no position
+	doc, err = ParseHTMLWithSourcePos(strings.NewReader("<div>x</p><p>y</p></div>"), "f")
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	ps := doc.Query("p")
+	if _, ok := ps[0].SourcePos(); ok {
+		t.Errorf("got: %v\nwant: none", ps[0])
+	}
+	if pos, _ := ps[1].SourcePos(); pos.String() != "f:1:11" {
+		t.Errorf("got: %v\nwant: %v", pos, "f:1:11")
+	}
+}
+
+func TestSourcePosMarker(t *testing.T) {
+	doc, err := ParseHTMLWithSourcePos(strings.NewReader(`<p haat-source-pos="x"><!--haat-source-pos:b0-->a</p>`), "f")
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	p := doc.Query("p")[0]
+	if actual := p.GetAttr("haat-source-pos"); actual != "x" {
+		t.Errorf("got: %v\nwant: %v", actual, "x")
+	}
+	if c := p.FirstChild; c == nil || c.Type != html.CommentNode || c.Data != "haat-source-pos:b0" {
+		t.Errorf("got: %v\nwant: the comment", c)
+	}
+	if pos, _ := p.SourcePos(); !doc.HasSourcePos() || pos.String() != "f:1:1" {
+		t.Errorf("got: %v\nwant: %v", pos, "f:1:1")
+	}
+
+	// a marker before the newline at the start of <pre> would keep the newline
+	doc, err = ParseHTMLWithSourcePos(strings.NewReader("<pre></b>\nx</pre>"), "f")
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	if actual := doc.Query("pre")[0].TextContent(); doc.HasSourcePos() || actual != "x" {
+		t.Errorf("got: %v %q\nwant: no positions and %q", doc.HasSourcePos(), actual, "x")
+	}
+	if doc.SourceFile() != "f" {
+		t.Errorf("got: %v\nwant: %v", doc.SourceFile(), "f")
+	}
+}
+
+func TestSourcePosDetached(t *testing.T) {
+	doc, err := ParseHTMLWithSourcePos(strings.NewReader("<div><p>x</p></div>"), "f")
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	p := doc.Query("p")[0]
+	p.Parent.RemoveChild((*html.Node)(p))
+	doc = nil
+	for range 3 {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if pos, ok := p.SourcePos(); !ok || pos.String() != "f:1:6" {
+		t.Errorf("got: %v\nwant: %v", pos, "f:1:6")
+	}
+}
+
+func TestSourcePosCollected(t *testing.T) {
+	doc, err := ParseHTMLWithSourcePos(strings.NewReader("<div><p>x</p></div>"), "f")
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	key := weak.Make((*html.Node)(doc.Query("p")[0]))
+	if _, ok := sourcePositions.Load(key); !ok {
+		t.Fatalf("got: none\nwant: the position of <p>")
+	}
+	doc = nil
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		runtime.GC()
+		if _, ok := sourcePositions.Load(key); !ok {
+			return
+		}
+	}
+	t.Errorf("got: the position of <p>\nwant: removed after the document is collected")
+}
+
+func TestIssues(t *testing.T) {
+	doc, err := ParseHTMLWithSourcePos(strings.NewReader("<p id=a></p>\n<p id=a></p><p id=a></p>"), "f")
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	var actual []string
+	for _, issue := range Issues(IDDuplicateCheck(doc.Query("html")[0])) {
+		actual = append(actual, issue.Error())
+	}
+	expected := []string{"f:2:1: duplicate id: a", "f:2:13: duplicate id: a"}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+	if actual := Issues(errors.New("x")); actual != nil {
+		t.Errorf("got: %v\nwant: %v", actual, nil)
+	}
+}
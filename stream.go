@@ -0,0 +1,262 @@
+package haat
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// FlushAttr is the attribute which marks an element to flush the output after it in RenderStream.
+const FlushAttr = "data-haat-flush"
+
+// LazyFunc fills the contents of a lazy element in RenderStream.
+// The attributes and the children of the element are restored after it is rendered,
+// so that the document can be rendered again. The original children are re-attached,
+// not copied, so changes made inside them are kept.
+type LazyFunc func(e *Element) error
+
+// StreamOptions specifies the options of RenderStream.
+type StreamOptions struct {
+	// Lazy maps the id of an element to the function which fills its contents.
+	// The output before the element is flushed before the function is called.
+	Lazy map[string]LazyFunc
+	// Checkers are applied to the document before rendering and again after filling each lazy element,
+	// so that the filled contents are checked with the rest of the page.
+	Checkers []Checker
+}
+
+// flusher flushes the writer if it supports http.Flusher or a Flush() error method.
+func flusher(w io.Writer) func() error {
+	switch f := w.(type) {
+	case http.Flusher:
+		return func() error {
+			f.Flush()
+			return nil
+		}
+	case interface{ Flush() error }:
+		return f.Flush
+	}
+	return func() error { return nil }
+}
+
+// streamer renders the document and flushes the output at the flush points.
+type streamer struct {
+	w       *bufio.Writer
+	flush   func() error
+	opts    StreamOptions
+	doc     *Document
+	descend map[*html.Node]bool
+}
+
+// check applies the checkers to the page.
+func (s *streamer) check() error {
+	for _, html := range s.doc.Query("html") {
+		for _, c := range s.opts.Checkers {
+			if err := c(html); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *streamer) flushAll() error {
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// isFlushPoint reports whether the output is flushed at the element.
+func (s *streamer) isFlushPoint(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if n.DataAtom == atom.Head && n.Namespace == "" {
+		return true
+	}
+	e := (*Element)(n)
+	if e.hasAttr(FlushAttr) {
+		return true
+	}
+	_, ok := s.opts.Lazy[e.ID()]
+	return ok && e.ID() != ""
+}
+
+// markDescend marks the ancestors of the flush points as the nodes which are rendered tag by tag.
+// Raw text elements are never descended.
+func (s *streamer) markDescend(n *html.Node) bool {
+	found := false
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if s.markDescend(c) {
+			found = true
+		}
+	}
+	if n.Type == html.ElementNode && childTextNodesAreLiteral(n) {
+		found = false
+	}
+	if found {
+		s.descend[n] = true
+	}
+	return found || s.isFlushPoint(n)
+}
+
+// childTextNodesAreLiteral is same as the function of x/net/html which renders text children without escaping.
+func childTextNodesAreLiteral(n *html.Node) bool {
+	if n.Namespace != "" {
+		return false
+	}
+	switch n.Data {
+	case "iframe", "noembed", "noframes", "noscript", "plaintext", "script", "style", "xmp":
+		return true
+	}
+	return false
+}
+
+// startTag returns the start tag of the element rendered by html.Render.
+func startTag(n *html.Node) (string, error) {
+	m := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      n.Attr,
+	}
+	var buf bytes.Buffer
+	if err := html.Render(&buf, m); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "</"+n.Data+">"), nil
+}
+
+// savedNode is the attributes and the children of a node saved to restore them.
+type savedNode struct {
+	attr     []html.Attribute
+	children []*html.Node
+}
+
+// save saves the attributes and the children of the node.
+func save(n *html.Node) savedNode {
+	saved := savedNode{attr: slices.Clone(n.Attr)}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		saved.children = append(saved.children, c)
+	}
+	return saved
+}
+
+// restore detaches the current children of the node and re-attaches the saved ones,
+// wherever they have been moved.
+func restore(n *html.Node, saved savedNode) {
+	for c := n.FirstChild; c != nil; c = n.FirstChild {
+		n.RemoveChild(c)
+	}
+	for _, c := range saved.children {
+		if c.Parent != nil {
+			c.Parent.RemoveChild(c)
+		}
+		n.AppendChild(c)
+	}
+	n.Attr = saved.attr
+}
+
+func (s *streamer) render(n *html.Node) error {
+	if n.Type == html.ElementNode {
+		if fill, ok := s.opts.Lazy[(*Element)(n).ID()]; ok && (*Element)(n).ID() != "" {
+			if err := s.flushAll(); err != nil {
+				return err
+			}
+			defer restore(n, save(n))
+			if err := fill((*Element)(n)); err != nil {
+				return err
+			}
+			if err := s.check(); err != nil {
+				return err
+			}
+			delete(s.descend, n)
+			s.markDescend(n)
+		}
+	}
+
+	if !s.descend[n] {
+		if err := html.Render(s.w, n); err != nil {
+			return err
+		}
+	} else {
+		if err := s.renderChildren(n); err != nil {
+			return err
+		}
+	}
+
+	if s.isFlushPoint(n) {
+		return s.flushAll()
+	}
+	return nil
+}
+
+// renderChildren renders the node tag by tag to reach the flush points in its children.
+func (s *streamer) renderChildren(n *html.Node) error {
+	if n.Type == html.ElementNode {
+		tag, err := startTag(n)
+		if err != nil {
+			return err
+		}
+		if _, err := s.w.WriteString(tag); err != nil {
+			return err
+		}
+		// html.Render adds a newline which would be ignored by the parser.
+		if c := n.FirstChild; c != nil && c.Type == html.TextNode && strings.HasPrefix(c.Data, "\n") {
+			switch n.Data {
+			case "pre", "listing", "textarea":
+				if err := s.w.WriteByte('\n'); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if err := s.render(c); err != nil {
+			return err
+		}
+	}
+	if n.Type == html.ElementNode {
+		if _, err := s.w.WriteString("</" + n.Data + ">"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderStream renders the document to the given writer flushing the output
+// after </head>, after each element marked with the data-haat-flush attribute
+// and before each lazy element. The writer is flushed when it implements
+// http.Flusher or has a Flush() error method, such as *bufio.Writer.
+//
+// An error after the first flush can not be reported to the client
+// because the earlier part of the page is already sent.
+//
+// RenderStream changes the document while the lazy elements are filled,
+// so it is not safe to use the document concurrently, even to render it.
+func (d *Document) RenderStream(w io.Writer, opts StreamOptions) error {
+	s := &streamer{
+		w:       bufio.NewWriter(w),
+		flush:   flusher(w),
+		opts:    opts,
+		doc:     d,
+		descend: map[*html.Node]bool{},
+	}
+	if err := s.check(); err != nil {
+		return err
+	}
+	s.markDescend((*html.Node)(d))
+	s.descend[(*html.Node)(d)] = true
+	if err := s.renderChildren((*html.Node)(d)); err != nil {
+		return err
+	}
+	return s.flushAll()
+}
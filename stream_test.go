@@ -0,0 +1,137 @@
+package haat
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html/atom"
+)
+
+// chunkWriter records the output written before each flush.
+type chunkWriter struct {
+	buf    bytes.Buffer
+	chunks []string
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *chunkWriter) Flush() error {
+	if w.buf.Len() > 0 {
+		w.chunks = append(w.chunks, w.buf.String())
+		w.buf.Reset()
+	}
+	return nil
+}
+
+func TestRenderStream(t *testing.T) {
+	ht, err := ParseHTML(strings.NewReader(`<!DOCTYPE html><html><head><title>Report</title></head>
+<body><header data-haat-flush>Top</header><pre>
+x</pre><main><div id="rows">loading</div><p>end &amp; more</p></main><script>a < b</script></body></html>`))
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+
+	loading := ht.Query("#rows")[0].FirstChild
+	var w chunkWriter
+	var wantBefore string
+	opts := StreamOptions{
+		Lazy: map[string]LazyFunc{
+			"rows": func(e *Element) error {
+				wantBefore = strings.Join(w.chunks, "")
+				if actual := e.InnerText(); actual != "loading" {
+					t.Errorf("got: %v\nwant: %v", actual, "loading")
+				}
+				e.ReplaceContents(T("1 < 2")).SetA(A("class", "done"))
+				return nil
+			},
+		},
+		Checkers: []Checker{IDDuplicateCheck},
+	}
+	var before bytes.Buffer
+	if err := ht.Render(&before); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	if err := ht.RenderStream(&w, opts); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+
+	expected := []string{
+		`<!DOCTYPE html><html><head><title>Report</title></head>`,
+		"\n<body><header data-haat-flush=\"\">Top</header>",
+		"<pre>x</pre><main>",
+		`<div class="done" id="rows">1 &lt; 2</div>`,
+		`<p>end &amp; more</p></main><script>a < b</script></body></html>`,
+	}
+	if strings.Join(w.chunks, "|") != strings.Join(expected, "|") {
+		t.Errorf("got: %q\nwant: %q", w.chunks, expected)
+	}
+	if wantBefore != strings.Join(expected[:3], "") {
+		t.Errorf("got: %q\nwant: %q", wantBefore, strings.Join(expected[:3], ""))
+	}
+
+	var buf bytes.Buffer
+	if err := ht.Render(&buf); err != nil {
+		t.Errorf("got: %v\nwant: %v", err, nil)
+	}
+	if actual := buf.String(); actual != before.String() {
+		t.Errorf("got: %v\nwant: %v", actual, before.String())
+	}
+
+	if actual := ht.Query("#rows")[0].FirstChild; actual != loading {
+		t.Errorf("got: %v\nwant: the original child", actual)
+	}
+
+	first := strings.Join(w.chunks, "")
+	w = chunkWriter{}
+	if err := ht.RenderStream(&w, opts); err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	if actual := strings.Join(w.chunks, ""); actual != first {
+		t.Errorf("got: %v\nwant: %v", actual, first)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := ht.RenderStream(rec, StreamOptions{}); err != nil || !rec.Flushed {
+		t.Errorf("got: %v, %v\nwant: flushed", err, rec.Flushed)
+	}
+}
+
+func TestRenderStreamChecksLazy(t *testing.T) {
+	ht, err := ParseHTML(strings.NewReader(`<!DOCTYPE html><html><body><p id="a">x</p><div id="rows"></div></body></html>`))
+	if err != nil {
+		t.Fatalf("got: %v\nwant: %v", err, nil)
+	}
+	var w chunkWriter
+	err = ht.RenderStream(&w, StreamOptions{
+		Lazy: map[string]LazyFunc{
+			"rows": func(e *Element) error {
+				e.C(E(atom.P).SetA(A("id", "b")), E(atom.P).SetA(A("id", "b")))
+				return nil
+			},
+		},
+		Checkers: []Checker{IDDuplicateCheck},
+	})
+	if err == nil || err.Error() != "duplicate id: b" {
+		t.Errorf("got: %v\nwant: %v", err, "duplicate id: b")
+	}
+	if actual := ht.Query("#rows")[0].FirstChild; actual != nil {
+		t.Errorf("got: %v\nwant: %v", actual, nil)
+	}
+
+	err = ht.RenderStream(&chunkWriter{}, StreamOptions{
+		Lazy: map[string]LazyFunc{
+			"rows": func(e *Element) error {
+				e.C(E(atom.P).SetA(A("id", "a")))
+				return nil
+			},
+		},
+		Checkers: []Checker{IDDuplicateCheck},
+	})
+	if err == nil || err.Error() != "duplicate id: a" {
+		t.Errorf("got: %v\nwant: %v", err, "duplicate id: a")
+	}
+}
@@ -0,0 +1,220 @@
+package haat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// styleDecl is a declaration of the style attribute.
+type styleDecl struct {
+	prop  string
+	value string
+}
+
+// splitStyle splits the CSS declaration list into declarations.
+// Semicolons in strings, parentheses and comments, and escaped characters are not treated as separators.
+func splitStyle(s string) []string {
+	var decls []string
+	var quote rune
+	depth := 0
+	start := 0
+	escaped := false
+	inComment := false
+	commentStart := 0
+	for i, c := range s {
+		switch {
+		case inComment:
+			// the "*/" is searched after the "/*", so that "/*/" does not close the comment
+			if c == '/' && i >= commentStart+3 && s[i-1] == '*' {
+				inComment = false
+			}
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			inComment = true
+			commentStart = i
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+		case c == ';' && depth == 0:
+			decls = append(decls, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(decls, s[start:])
+}
+
+// parseStyle parses the value of the style attribute.
+func parseStyle(s string) []styleDecl {
+	var decls []styleDecl
+	for _, d := range splitStyle(s) {
+		prop, value, ok := strings.Cut(d, ":")
+		if !ok {
+			continue
+		}
+		prop = strings.TrimSpace(prop)
+		if prop == "" {
+			continue
+		}
+		if !strings.HasPrefix(prop, "--") {
+			prop = lower(prop)
+		}
+		decls = append(decls, styleDecl{prop: prop, value: strings.TrimSpace(value)})
+	}
+	return decls
+}
+
+// serializeStyle serializes the declarations to the value of the style attribute.
+func serializeStyle(decls []styleDecl) string {
+	ss := make([]string, len(decls))
+	for i, d := range decls {
+		ss[i] = d.prop + ": " + d.value
+	}
+	return strings.Join(ss, "; ")
+}
+
+// cssEscape escapes the character as a CSS hex escape.
+func cssEscape(b *strings.Builder, c rune) {
+	fmt.Fprintf(b, "\\%x ", c)
+}
+
+// escapeStyleProp escapes the property name so that it is a single CSS identifier.
+func escapeStyleProp(prop string) string {
+	var b strings.Builder
+	for _, c := range prop {
+		switch {
+		case c == '-' || c == '_' || c >= 0x80,
+			'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+			b.WriteRune(c)
+		default:
+			cssEscape(&b, c)
+		}
+	}
+	return b.String()
+}
+
+// escapeStyleValue escapes the value so that it can not end the declaration
+// or affect other declarations of the style attribute.
+func escapeStyleValue(value string) string {
+	value = strings.TrimSpace(value)
+	depth := 0
+	balanced := true
+	var quote rune
+	for _, c := range value {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				balanced = false
+			}
+		}
+	}
+	balanced = balanced && depth == 0
+	closed := quote == 0
+
+	var b strings.Builder
+	for i, c := range value {
+		switch {
+		case c == ';' || c == '{' || c == '}' || c == '\\' || c == '<' || c == '>' || c < 0x20 || c == 0x7f:
+			cssEscape(&b, c)
+		case (c == '"' || c == '\'') && !closed:
+			cssEscape(&b, c)
+		case c == '/' && strings.HasPrefix(value[i:], "/*"), c == '*' && i > 0 && value[i-1] == '/':
+			cssEscape(&b, c)
+		case (c == '(' || c == ')') && !balanced:
+			cssEscape(&b, c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// Styles returns the declarations of the style attribute as a map.
+// If a property is declared more than once, the last value is returned.
+func (e *Element) Styles() map[string]string {
+	styles := map[string]string{}
+	for _, d := range parseStyle(e.GetAttr("style")) {
+		styles[d.prop] = d.value
+	}
+	return styles
+}
+
+// Style returns the value of the given property in the style attribute.
+func (e *Element) Style(prop string) string {
+	return e.Styles()[normalizeStyleProp(prop)]
+}
+
+func normalizeStyleProp(prop string) string {
+	prop = strings.TrimSpace(prop)
+	if !strings.HasPrefix(prop, "--") {
+		prop = lower(prop)
+	}
+	return escapeStyleProp(prop)
+}
+
+// SetStyle sets the property in the style attribute to the given value.
+// The value is escaped so that it can not break out into other declarations.
+// The existing declaration keeps its position and a new one is appended.
+func (e *Element) SetStyle(prop, value string) *Element {
+	prop = normalizeStyleProp(prop)
+	value = escapeStyleValue(value)
+	if prop == "" || value == "" {
+		return e.RemoveStyle(prop)
+	}
+
+	var decls []styleDecl
+	found := false
+	for _, d := range parseStyle(e.GetAttr("style")) {
+		if d.prop == prop {
+			if found {
+				continue
+			}
+			d.value = value
+			found = true
+		}
+		decls = append(decls, d)
+	}
+	if !found {
+		decls = append(decls, styleDecl{prop: prop, value: value})
+	}
+	return e.SetA(NewAttribute("style", serializeStyle(decls)))
+}
+
+// RemoveStyle removes the property from the style attribute.
+// The style attribute is removed when no declaration is left.
+func (e *Element) RemoveStyle(prop string) *Element {
+	if !e.hasAttr("style") {
+		return e
+	}
+	prop = normalizeStyleProp(prop)
+	var decls []styleDecl
+	for _, d := range parseStyle(e.GetAttr("style")) {
+		if d.prop != prop {
+			decls = append(decls, d)
+		}
+	}
+	if len(decls) == 0 {
+		return e.RemoveAttr("style")
+	}
+	return e.SetA(NewAttribute("style", serializeStyle(decls)))
+}
@@ -0,0 +1,66 @@
+package haat
+
+import (
+	"maps"
+	"testing"
+
+	"golang.org/x/net/html/atom"
+)
+
+func TestStyle(t *testing.T) {
+	div := NewElement(atom.Div).SetA(A("style", `DISPLAY:none; background: url("a;b.png") ;--Gap:1px;`))
+
+	expected := map[string]string{"display": "none", "background": `url("a;b.png")`, "--Gap": "1px"}
+	if actual := div.Styles(); !maps.Equal(actual, expected) {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+	if actual := div.Style("Display"); actual != "none" {
+		t.Errorf("got: %v\nwant: %v", actual, "none")
+	}
+
+	commented := NewElement(atom.Div).SetA(A("style", `color: red /*/ ; color: blue; */; margin: 0 /**/`))
+	expected3 := map[string]string{"color": "red /*/ ; color: blue; */", "margin": "0 /**/"}
+	if actual := commented.Styles(); !maps.Equal(actual, expected3) {
+		t.Errorf("got: %v\nwant: %v", actual, expected3)
+	}
+
+	div.SetStyle("display", "block").SetStyle("width", "calc(100% - 2px)").RemoveStyle("background")
+	expected2 := `display: block; --Gap: 1px; width: calc(100% - 2px)`
+	if actual := div.GetAttr("style"); actual != expected2 {
+		t.Errorf("got: %v\nwant: %v", actual, expected2)
+	}
+
+	div.RemoveStyle("display").RemoveStyle("--Gap").RemoveStyle("width")
+	if len(div.Attr) != 0 {
+		t.Errorf("got: %v\nwant: %v", div.Attr, nil)
+	}
+}
+
+func TestSetStyleEscape(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"plain", "red", `color: red`},
+		{"semicolon", "red; background: url(evil)", `color: red\3b  background: url(evil)`},
+		{"braces", "red}body{color:blue", `color: red\7d body\7b color:blue`},
+		{"unclosed quote", `"red`, `color: \22 red`},
+		{"closed quote", `"a;b"`, `color: "a\3b b"`},
+		{"unbalanced paren", "url(x", `color: url\28 x`},
+		{"comment", "red/*", `color: red\2f \2a `},
+		{"newline", "red\nx", `color: red\a x`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			div := NewElement(atom.Div).SetStyle("color", tt.value)
+			if actual := div.GetAttr("style"); actual != tt.want {
+				t.Errorf("got: %v\nwant: %v", actual, tt.want)
+			}
+			if n := len(div.Styles()); n != 1 {
+				t.Errorf("got: %v declarations\nwant: %v", n, 1)
+			}
+		})
+	}
+}